@@ -0,0 +1,162 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"time"
+
+	proxyAPI "github.com/clearcontainers/proxy/api"
+	"github.com/sirupsen/logrus"
+)
+
+// The agent (hyperstart) runs inside the guest image, so its version
+// cannot be queried before the image boots and mismatched image/agent
+// combinations used to surface only as opaque hangs. The image build
+// declares the shipped agent version in the configuration instead, and
+// the runtime checks it against the protocol generation it speaks
+// before booting anything.
+
+// agentVersion is the agent version declared for the configured guest
+// image, set from the configuration.
+var agentVersion = unknown
+
+// refuseIncompatibleAgent is set from the configuration. When enabled,
+// an agent version with the wrong major version fails pod creation
+// instead of just being logged.
+var refuseIncompatibleAgent bool
+
+// Agent health values reported in the state command output.
+const (
+	agentHealthy     = "healthy"
+	agentUnreachable = "unreachable"
+)
+
+// agentHealthAnnotation and agentVersionAnnotation carry the agent
+// details in the state command output.
+const (
+	agentHealthAnnotation  = ccAnnotationsPrefix + "agent_health"
+	agentVersionAnnotation = ccAnnotationsPrefix + "agent_version"
+)
+
+// agentProbeTimeout bounds the health probe so the state command
+// cannot hang on a wedged proxy.
+const agentProbeTimeout = 2 * time.Second
+
+// agentPingRetries and agentRequestTimeout are set from the
+// configuration. Both default to zero, which disables the ping and
+// leaves agent operations unbounded, preserving the historical
+// behaviour unless the policy is configured.
+var agentPingRetries int
+var agentRequestTimeout time.Duration
+
+// agentPingInterval is the pause between ping attempts, giving a
+// temporarily busy agent a chance to recover instead of burning all
+// retries back to back ("var" to allow tests to modify it).
+var agentPingInterval = 500 * time.Millisecond
+
+// pingAgent verifies that the agent channel of the specified pod
+// accepts connections before a long operation is attempted, retrying a
+// configurable number of times. A wedged agent is reported as a
+// retriable agent timeout instead of the operation hanging forever.
+func pingAgent(podID string) error {
+	if agentPingRetries == 0 {
+		return nil
+	}
+
+	for i := 0; i < agentPingRetries; i++ {
+		if i > 0 {
+			time.Sleep(agentPingInterval)
+		}
+
+		if probeAgentHealth(podID) == agentHealthy {
+			return nil
+		}
+	}
+
+	return agentTimeoutError(fmt.Errorf("agent of pod %v unreachable after %d attempts", podID, agentPingRetries))
+}
+
+// withAgentTimeout bounds the specified agent operation by the
+// configured request timeout. On expiry the operation is abandoned (it
+// dies with the process) and a retriable agent timeout is returned, so
+// a wedged agent produces a clean, fast error rather than an
+// indefinite hang.
+func withAgentTimeout(operation string, op func() error) error {
+	if agentRequestTimeout == 0 {
+		return op()
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- op()
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(agentRequestTimeout):
+		return agentTimeoutError(fmt.Errorf("%s timed out after %v", operation, agentRequestTimeout))
+	}
+}
+
+// checkAgentCompatibility verifies that the declared agent version
+// matches the protocol generation this runtime speaks. An undeclared
+// version cannot be judged and is accepted silently.
+func checkAgentCompatibility() error {
+	if agentVersion == "" || agentVersion == unknown {
+		return nil
+	}
+
+	major := strings.SplitN(agentVersion, ".", 2)[0]
+	expected := fmt.Sprintf("%d", proxyAPI.Version)
+
+	if major == expected {
+		return nil
+	}
+
+	fields := logrus.Fields{
+		"agent-version":    agentVersion,
+		"expected-version": expected + ".x",
+	}
+
+	if refuseIncompatibleAgent {
+		return fmt.Errorf("agent version %v is incompatible with this runtime (expected %v.x)", agentVersion, expected)
+	}
+
+	ccLog.WithFields(fields).Warn("agent version may be incompatible with this runtime")
+
+	return nil
+}
+
+// probeAgentHealth reports the runtime's view of the agent channel for
+// the specified pod: the agent is reachable when the per-pod proxy
+// socket accepts connections.
+func probeAgentHealth(podID string) string {
+	socket := filepath.Join(podRunStoragePath, podID, "proxy.sock")
+
+	conn, err := net.DialTimeout("unix", socket, agentProbeTimeout)
+	if err != nil {
+		return agentUnreachable
+	}
+
+	conn.Close()
+
+	return agentHealthy
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	proxyAPI "github.com/clearcontainers/proxy/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAgentCompatibility(t *testing.T) {
+	assert := assert.New(t)
+
+	savedAgentVersion := agentVersion
+	savedRefuse := refuseIncompatibleAgent
+
+	defer func() {
+		agentVersion = savedAgentVersion
+		refuseIncompatibleAgent = savedRefuse
+	}()
+
+	// undeclared versions cannot be judged
+	for _, version := range []string{"", unknown} {
+		agentVersion = version
+		refuseIncompatibleAgent = true
+		assert.NoError(checkAgentCompatibility())
+	}
+
+	// matching major version
+	agentVersion = fmt.Sprintf("%d.0.1", proxyAPI.Version)
+	assert.NoError(checkAgentCompatibility())
+
+	// mismatched major version: warning only by default
+	agentVersion = fmt.Sprintf("%d.0", proxyAPI.Version+1)
+	refuseIncompatibleAgent = false
+	assert.NoError(checkAgentCompatibility())
+
+	// mismatched major version: error when configured to refuse
+	refuseIncompatibleAgent = true
+	assert.Error(checkAgentCompatibility())
+}
+
+func TestProbeAgentHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+
+	// no proxy socket
+	assert.Equal(agentUnreachable, probeAgentHealth(podID))
+
+	err = os.MkdirAll(filepath.Join(tmpdir, podID), 0750)
+	assert.NoError(err)
+
+	listener, err := net.Listen("unix", filepath.Join(tmpdir, podID, "proxy.sock"))
+	assert.NoError(err)
+	defer listener.Close()
+
+	assert.Equal(agentHealthy, probeAgentHealth(podID))
+}
+
+func TestPingAgent(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+	savedAgentPingRetries := agentPingRetries
+	savedAgentPingInterval := agentPingInterval
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+		agentPingRetries = savedAgentPingRetries
+		agentPingInterval = savedAgentPingInterval
+	}()
+
+	podRunStoragePath = tmpdir
+
+	// keep the retry pauses from slowing the test down
+	agentPingInterval = time.Millisecond
+
+	const podID = "pod"
+
+	// disabled by default
+	agentPingRetries = 0
+	assert.NoError(pingAgent(podID))
+
+	// enabled, no proxy socket
+	agentPingRetries = 2
+	err = pingAgent(podID)
+	assert.Error(err)
+	assert.Equal(exitAgentTimeout, errorExitCode(err))
+
+	// enabled, live proxy socket
+	err = os.MkdirAll(filepath.Join(tmpdir, podID), 0750)
+	assert.NoError(err)
+
+	listener, err := net.Listen("unix", filepath.Join(tmpdir, podID, "proxy.sock"))
+	assert.NoError(err)
+	defer listener.Close()
+
+	assert.NoError(pingAgent(podID))
+}
+
+func TestWithAgentTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	savedAgentRequestTimeout := agentRequestTimeout
+
+	defer func() {
+		agentRequestTimeout = savedAgentRequestTimeout
+	}()
+
+	// unbounded by default
+	agentRequestTimeout = 0
+	assert.NoError(withAgentTimeout("op", func() error { return nil }))
+
+	// operations finishing in time pass their result through
+	agentRequestTimeout = time.Second
+	assert.NoError(withAgentTimeout("op", func() error { return nil }))
+
+	opErr := fmt.Errorf("operation failed")
+	err := withAgentTimeout("op", func() error { return opErr })
+	assert.Equal(opErr, err)
+
+	// expired operations become retriable agent timeouts
+	agentRequestTimeout = time.Millisecond
+	err = withAgentTimeout("op", func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	assert.Error(err)
+	assert.Equal(exitAgentTimeout, errorExitCode(err))
+}
@@ -22,9 +22,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 
 	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
 	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
@@ -51,13 +53,18 @@ const (
 	successMessageCreate  = "System can currently create " + project
 	failMessage           = "System is not capable of running " + project
 	kernelPropertyCorrect = "Kernel property value correct"
+
+	// minimum number of bits the host entropy pool should hold for
+	// guests to boot without stalling on their own pool initialisation
+	minHostEntropy = 1000
 )
 
 // variables rather than consts to allow tests to modify them
 var (
-	procCPUInfo  = "/proc/cpuinfo"
-	sysModuleDir = "/sys/module"
-	modInfoCmd   = "modinfo"
+	procCPUInfo      = "/proc/cpuinfo"
+	sysModuleDir     = "/sys/module"
+	modInfoCmd       = "modinfo"
+	procEntropyAvail = "/proc/sys/kernel/random/entropy_avail"
 )
 
 // getCPUInfo returns details of the first CPU read from the specified cpuinfo file
@@ -231,6 +238,34 @@ func checkKernelModules(modules map[string]kernelModule, handler kernelParamHand
 	return count, nil
 }
 
+// checkHostEntropyLevel warns if the host entropy pool is too shallow
+// to seed the guest virtio-rng devices without stalling guest boot.
+// A low level is not an error: the pool refills over time.
+func checkHostEntropyLevel() error {
+	text, err := getFileContents(procEntropyAvail)
+	if err != nil {
+		return err
+	}
+
+	available, err := strconv.Atoi(strings.TrimSpace(text))
+	if err != nil {
+		return err
+	}
+
+	fields := logrus.Fields{
+		"entropy-available": available,
+		"entropy-required":  minHostEntropy,
+	}
+
+	if available < minHostEntropy {
+		ccLog.WithFields(fields).Warn("host entropy is low, guest boot may stall")
+	} else {
+		ccLog.WithFields(fields).Info("host entropy level is sufficient")
+	}
+
+	return nil
+}
+
 // hostIsVMContainerCapable checks to see if the host is theoretically capable
 // of creating a VM container.
 func hostIsVMContainerCapable(details vmContainerCapableDetails) error {
@@ -290,6 +325,24 @@ var ccCheckCLICommand = cli.Command{
 
 		ccLog.Info(successMessageCapable)
 
+		if err := checkHostEntropyLevel(); err != nil {
+			return err
+		}
+
+		// probe the hypervisor the configuration actually selects,
+		// falling back to the default when no configuration was loaded
+		hypervisorPath := defaultHypervisorPath
+		if runtimeConfig, ok := context.App.Metadata["runtimeConfig"].(oci.RuntimeConfig); ok {
+			hypervisorPath = runtimeConfig.HypervisorConfig.HypervisorPath
+		}
+
+		if err := checkNVDIMMSupport(hypervisorPath); err != nil {
+			// the hypervisor may be missing or not executable; that
+			// makes the NVDIMM probe impossible, not the system
+			// incapable, so only warn.
+			ccLog.WithError(err).Warn("cannot determine NVDIMM support")
+		}
+
 		if os.Geteuid() == 0 {
 			err = archHostCanCreateVMContainer()
 			if err != nil {
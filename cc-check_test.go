@@ -619,6 +619,49 @@ func TestCCCheckCLIFunctionFail(t *testing.T) {
 	assert.Error(err)
 }
 
+func TestCheckHostEntropyLevel(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	oldProcEntropyAvail := procEntropyAvail
+
+	// doesn't exist
+	procEntropyAvail = filepath.Join(dir, "entropy_avail")
+
+	defer func() {
+		procEntropyAvail = oldProcEntropyAvail
+	}()
+
+	err = checkHostEntropyLevel()
+	assert.Error(err)
+
+	// invalid contents
+	err = createFile(procEntropyAvail, "not-a-number\n")
+	assert.NoError(err)
+
+	err = checkHostEntropyLevel()
+	assert.Error(err)
+
+	// low entropy (only logs a warning)
+	err = createFile(procEntropyAvail, "10\n")
+	assert.NoError(err)
+
+	err = checkHostEntropyLevel()
+	assert.NoError(err)
+
+	// sufficient entropy
+	err = createFile(procEntropyAvail, "4096\n")
+	assert.NoError(err)
+
+	err = checkHostEntropyLevel()
+	assert.NoError(err)
+}
+
 func TestCheckKernelParamHandler(t *testing.T) {
 	assert := assert.New(t)
 
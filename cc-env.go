@@ -30,7 +30,7 @@ import (
 //
 // XXX: Increment for every change to the output format
 // (meaning any change to the EnvInfo type).
-const formatVersion = "1.0.9"
+const formatVersion = "1.0.12"
 
 // MetaInfo stores information on the format of the output itself
 type MetaInfo struct {
@@ -76,11 +76,13 @@ type RuntimeVersionInfo struct {
 
 // HypervisorInfo stores hypervisor details
 type HypervisorInfo struct {
-	MachineType       string
-	Version           string
-	Path              string
-	Debug             bool
-	BlockDeviceDriver string
+	MachineType           string
+	Version               string
+	Path                  string
+	Debug                 bool
+	BlockDeviceDriver     string
+	DisableBlockDeviceUse bool
+	EntropySource         string
 }
 
 // ProxyInfo stores proxy details
@@ -254,8 +256,11 @@ func getShimInfo(config oci.RuntimeConfig) (ShimInfo, error) {
 
 func getAgentInfo(config oci.RuntimeConfig) AgentInfo {
 	ccAgent := AgentInfo{
-		Type:    string(config.AgentType),
-		Version: unknown,
+		Type: string(config.AgentType),
+
+		// as declared for the configured guest image; the agent
+		// itself cannot be queried before a VM boots
+		Version: agentVersion,
 	}
 
 	return ccAgent
@@ -270,10 +275,12 @@ func getHypervisorInfo(config oci.RuntimeConfig) HypervisorInfo {
 	}
 
 	return HypervisorInfo{
-		MachineType:       config.HypervisorConfig.HypervisorMachineType,
-		Version:           version,
-		Path:              hypervisorPath,
-		BlockDeviceDriver: config.HypervisorConfig.BlockDeviceDriver,
+		MachineType:           config.HypervisorConfig.HypervisorMachineType,
+		Version:               version,
+		Path:                  hypervisorPath,
+		BlockDeviceDriver:     config.HypervisorConfig.BlockDeviceDriver,
+		DisableBlockDeviceUse: config.HypervisorConfig.DisableBlockDeviceUse,
+		EntropySource:         hypervisorEntropySource,
 	}
 }
 
@@ -155,7 +155,7 @@ func getExpectedShimDetails(config oci.RuntimeConfig) (ShimInfo, error) {
 func getExpectedAgentDetails(config oci.RuntimeConfig) (AgentInfo, error) {
 	return AgentInfo{
 		Type:    string(config.AgentType),
-		Version: unknown,
+		Version: agentVersion,
 	}, nil
 }
 
@@ -234,10 +234,12 @@ model name	: %s
 
 func getExpectedHypervisor(config oci.RuntimeConfig) HypervisorInfo {
 	return HypervisorInfo{
-		Version:           testHypervisorVersion,
-		Path:              config.HypervisorConfig.HypervisorPath,
-		MachineType:       config.HypervisorConfig.HypervisorMachineType,
-		BlockDeviceDriver: config.HypervisorConfig.BlockDeviceDriver,
+		Version:               testHypervisorVersion,
+		Path:                  config.HypervisorConfig.HypervisorPath,
+		MachineType:           config.HypervisorConfig.HypervisorMachineType,
+		BlockDeviceDriver:     config.HypervisorConfig.BlockDeviceDriver,
+		DisableBlockDeviceUse: config.HypervisorConfig.DisableBlockDeviceUse,
+		EntropySource:         hypervisorEntropySource,
 	}
 }
 
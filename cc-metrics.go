@@ -0,0 +1,285 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/urfave/cli"
+)
+
+// procDir is the mount point of the proc filesystem ("var" to allow
+// tests to modify it).
+var procDir = "/proc"
+
+// PodMetrics stores the runtime metrics gathered for a single pod.
+type PodMetrics struct {
+	// pod identifier
+	ID string `json:"id"`
+
+	// number of workload containers in the pod (the CRI infra
+	// container, if any, is not counted)
+	Containers int `json:"containers"`
+
+	// host process ID of the VM, zero if it could not be found
+	VMPid int `json:"vm_pid"`
+
+	// resident set size of the VM process in KiB
+	VMRSSKiB int64 `json:"vm_rss_kib"`
+
+	// sum of the memory limits of all containers in the pod in
+	// bytes, zero if no limits are set
+	WorkloadMemBytes int64 `json:"workload_mem_bytes"`
+
+	// sum of the pids limits of all containers in the pod, zero if
+	// no limits are set
+	WorkloadPidsLimit int64 `json:"workload_pids_limit"`
+}
+
+// MetricsInfo collects all information that will be displayed by the
+// metrics command.
+type MetricsInfo struct {
+	// total number of pods
+	Pods int `json:"pods"`
+
+	// total number of containers
+	Containers int `json:"containers"`
+
+	// sum of the resident set sizes of all pod VMs in KiB
+	VMRSSTotalKiB int64 `json:"vm_rss_total_kib"`
+
+	Pod []PodMetrics `json:"pod"`
+}
+
+// findVMPid scans the proc filesystem for the hypervisor process
+// handling the specified pod. It returns zero if no such process can
+// be found, which is not an error: the pod may simply not be running.
+func findVMPid(podID string) int {
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return 0
+	}
+
+	hypervisorName := filepath.Base(defaultHypervisorPath)
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		bytes, err := ioutil.ReadFile(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+
+		cmdline := strings.Replace(string(bytes), "\x00", " ", -1)
+
+		if !strings.Contains(cmdline, podID) {
+			continue
+		}
+
+		args := strings.Fields(cmdline)
+		if len(args) == 0 {
+			continue
+		}
+
+		// The hypervisor is launched with the pod ID on its command
+		// line (for the QMP socket and VM name).
+		if strings.Contains(filepath.Base(args[0]), hypervisorName) ||
+			strings.Contains(filepath.Base(args[0]), "qemu") {
+			return pid
+		}
+	}
+
+	return 0
+}
+
+// getProcessRSSKiB returns the resident set size of the specified
+// process in KiB.
+func getProcessRSSKiB(pid int) (int64, error) {
+	contents, err := getFileContents(filepath.Join(procDir, strconv.Itoa(pid), "status"))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			break
+		}
+
+		return strconv.ParseInt(fields[1], 10, 64)
+	}
+
+	return 0, fmt.Errorf("cannot determine RSS of process %d", pid)
+}
+
+// getMetricsInfo gathers pod density and memory footprint details for
+// all pods known to the runtime.
+func getMetricsInfo() (MetricsInfo, error) {
+	podStatusList, err := vci.ListPod()
+	if err != nil {
+		return MetricsInfo{}, err
+	}
+
+	info := MetricsInfo{
+		// ensure "[]" (not "null") in the JSON output when no pods
+		// are running
+		Pod: []PodMetrics{},
+	}
+
+	for _, podStatus := range podStatusList {
+		podMetrics := PodMetrics{
+			ID: podStatus.ID,
+		}
+
+		for _, containerStatus := range podStatus.ContainersStatus {
+			ociSpec, err := oci.GetOCIConfig(containerStatus)
+			if err != nil {
+				podMetrics.Containers++
+				continue
+			}
+
+			// the CRI infra container is pure overhead, not workload
+			if isInfraContainer(ociSpec) {
+				continue
+			}
+
+			podMetrics.Containers++
+
+			if ociSpec.Linux != nil &&
+				ociSpec.Linux.Resources != nil &&
+				ociSpec.Linux.Resources.Memory != nil &&
+				ociSpec.Linux.Resources.Memory.Limit != nil {
+				podMetrics.WorkloadMemBytes += *ociSpec.Linux.Resources.Memory.Limit
+			}
+
+			if ociSpec.Linux != nil &&
+				ociSpec.Linux.Resources != nil &&
+				ociSpec.Linux.Resources.Pids != nil &&
+				ociSpec.Linux.Resources.Pids.Limit > 0 {
+				podMetrics.WorkloadPidsLimit += ociSpec.Linux.Resources.Pids.Limit
+			}
+		}
+
+		if pid := findVMPid(podStatus.ID); pid != 0 {
+			podMetrics.VMPid = pid
+
+			if rss, err := getProcessRSSKiB(pid); err == nil {
+				podMetrics.VMRSSKiB = rss
+			}
+		}
+
+		info.Pods++
+		info.Containers += podMetrics.Containers
+		info.VMRSSTotalKiB += podMetrics.VMRSSKiB
+
+		info.Pod = append(info.Pod, podMetrics)
+	}
+
+	return info, nil
+}
+
+// writeMetricsTextfile writes the metrics in the text-based exposition
+// format understood by the Prometheus node_exporter textfile
+// collector.
+func writeMetricsTextfile(info MetricsInfo, w io.Writer) error {
+	prefix := strings.Replace(name, "-", "_", -1)
+
+	fmt.Fprintf(w, "# HELP %s_pods Number of pods managed by the runtime.\n", prefix)
+	fmt.Fprintf(w, "# TYPE %s_pods gauge\n", prefix)
+	fmt.Fprintf(w, "%s_pods %d\n", prefix, info.Pods)
+
+	fmt.Fprintf(w, "# HELP %s_containers Number of containers managed by the runtime.\n", prefix)
+	fmt.Fprintf(w, "# TYPE %s_containers gauge\n", prefix)
+	fmt.Fprintf(w, "%s_containers %d\n", prefix, info.Containers)
+
+	fmt.Fprintf(w, "# HELP %s_vm_rss_kib Resident set size of a pod VM in KiB.\n", prefix)
+	fmt.Fprintf(w, "# TYPE %s_vm_rss_kib gauge\n", prefix)
+
+	for _, pod := range info.Pod {
+		fmt.Fprintf(w, "%s_vm_rss_kib{pod=%q} %d\n", prefix, pod.ID, pod.VMRSSKiB)
+	}
+
+	fmt.Fprintf(w, "# HELP %s_workload_mem_bytes Combined memory limit of all containers in a pod.\n", prefix)
+	fmt.Fprintf(w, "# TYPE %s_workload_mem_bytes gauge\n", prefix)
+
+	for _, pod := range info.Pod {
+		fmt.Fprintf(w, "%s_workload_mem_bytes{pod=%q} %d\n", prefix, pod.ID, pod.WorkloadMemBytes)
+	}
+
+	fmt.Fprintf(w, "# HELP %s_workload_pids_limit Combined pids limit of all containers in a pod.\n", prefix)
+	fmt.Fprintf(w, "# TYPE %s_workload_pids_limit gauge\n", prefix)
+
+	for _, pod := range info.Pod {
+		fmt.Fprintf(w, "%s_workload_pids_limit{pod=%q} %d\n", prefix, pod.ID, pod.WorkloadPidsLimit)
+	}
+
+	return nil
+}
+
+var ccMetricsCLICommand = cli.Command{
+	Name:  metricsCmd,
+	Usage: "display pod density and memory footprint metrics",
+	Description: `Reports, for every pod, the VM resident set size, the combined
+   memory limit of the pod's containers, and pod/container counts. The
+   default output format is JSON. With --textfile, the metrics are
+   written to the specified file in the Prometheus node_exporter
+   textfile collector format instead.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "textfile",
+			Usage: "write metrics to the specified `FILE` in Prometheus textfile format",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		info, err := getMetricsInfo()
+		if err != nil {
+			return err
+		}
+
+		if textfile := context.String("textfile"); textfile != "" {
+			f, err := os.OpenFile(textfile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			return writeMetricsTextfile(info, f)
+		}
+
+		bytes, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(defaultOutputFile, "%s\n", bytes)
+
+		return nil
+	},
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/stretchr/testify/assert"
+)
+
+// createFakeVMProc populates a fake proc filesystem entry for a
+// hypervisor process handling the specified pod.
+func createFakeVMProc(procRoot string, pid int, podID string, rssKiB int64) error {
+	pidDir := filepath.Join(procRoot, fmt.Sprintf("%d", pid))
+
+	if err := os.MkdirAll(pidDir, testDirMode); err != nil {
+		return err
+	}
+
+	cmdline := strings.Join([]string{
+		"/usr/bin/qemu-system-x86_64",
+		"-name",
+		podID,
+	}, "\x00")
+
+	if err := createFile(filepath.Join(pidDir, "cmdline"), cmdline); err != nil {
+		return err
+	}
+
+	status := fmt.Sprintf("Name:\tqemu-system-x86\nVmRSS:\t%d kB\n", rssKiB)
+
+	return createFile(filepath.Join(pidDir, "status"), status)
+}
+
+func TestMetricsFindVMPid(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedProcDir := procDir
+
+	defer func() {
+		procDir = savedProcDir
+	}()
+
+	procDir = tmpdir
+
+	// no processes at all
+	assert.Equal(0, findVMPid(testPodID))
+
+	err = createFakeVMProc(procDir, 4242, testPodID, 1024)
+	assert.NoError(err)
+
+	assert.Equal(4242, findVMPid(testPodID))
+	assert.Equal(0, findVMPid("no-such-pod"))
+}
+
+func TestMetricsGetProcessRSSKiB(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedProcDir := procDir
+
+	defer func() {
+		procDir = savedProcDir
+	}()
+
+	procDir = tmpdir
+
+	_, err = getProcessRSSKiB(1234)
+	assert.Error(err)
+
+	err = createFakeVMProc(procDir, 1234, testPodID, 2048)
+	assert.NoError(err)
+
+	rss, err := getProcessRSSKiB(1234)
+	assert.NoError(err)
+	assert.Equal(int64(2048), rss)
+}
+
+func TestMetricsGetMetricsInfo(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedProcDir := procDir
+
+	defer func() {
+		procDir = savedProcDir
+	}()
+
+	procDir = tmpdir
+
+	err = createFakeVMProc(procDir, 999, testPodID, 4096)
+	assert.NoError(err)
+
+	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
+		return []vc.PodStatus{
+			{
+				ID: testPodID,
+				ContainersStatus: []vc.ContainerStatus{
+					{ID: testContainerID},
+				},
+			},
+		}, nil
+	}
+
+	defer func() {
+		testingImpl.ListPodFunc = nil
+	}()
+
+	info, err := getMetricsInfo()
+	assert.NoError(err)
+
+	assert.Equal(1, info.Pods)
+	assert.Equal(1, info.Containers)
+	assert.Equal(int64(4096), info.VMRSSTotalKiB)
+
+	assert.Len(info.Pod, 1)
+	assert.Equal(testPodID, info.Pod[0].ID)
+	assert.Equal(999, info.Pod[0].VMPid)
+	assert.Equal(int64(4096), info.Pod[0].VMRSSKiB)
+}
+
+func TestMetricsWriteMetricsTextfile(t *testing.T) {
+	assert := assert.New(t)
+
+	info := MetricsInfo{
+		Pods:          2,
+		Containers:    3,
+		VMRSSTotalKiB: 8192,
+		Pod: []PodMetrics{
+			{ID: "pod1", Containers: 2, VMRSSKiB: 4096, WorkloadMemBytes: 1024, WorkloadPidsLimit: 512},
+			{ID: "pod2", Containers: 1, VMRSSKiB: 4096},
+		},
+	}
+
+	buf := &bytes.Buffer{}
+
+	err := writeMetricsTextfile(info, buf)
+	assert.NoError(err)
+
+	output := buf.String()
+	prefix := strings.Replace(name, "-", "_", -1)
+
+	for _, line := range []string{
+		fmt.Sprintf("%s_pods 2", prefix),
+		fmt.Sprintf("%s_containers 3", prefix),
+		fmt.Sprintf("%s_vm_rss_kib{pod=\"pod1\"} 4096", prefix),
+		fmt.Sprintf("%s_workload_mem_bytes{pod=\"pod1\"} 1024", prefix),
+		fmt.Sprintf("%s_workload_pids_limit{pod=\"pod1\"} 512", prefix),
+	} {
+		assert.True(strings.Contains(output, line), "expected %q in output", line)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/urfave/cli"
+)
+
+var ccShutdownAllCLICommand = cli.Command{
+	Name:  shutdownAllCmd,
+	Usage: "gracefully shut down all pods",
+	Description: `Stops the workloads of every pod known to the runtime, powers off the
+   VMs and removes their state. Intended for host shutdown paths where
+   leaving qemu processes and stale state directories behind is not
+   acceptable. With --force, pods whose workloads cannot be stopped
+   cleanly are still powered off and cleaned up.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name:  "force, f",
+			Usage: "continue shutting down a pod even if its workloads cannot be stopped cleanly",
+		},
+		cli.UintFlag{
+			Name:  "parallel, p",
+			Value: 1,
+			Usage: "number of pods to shut down concurrently",
+		},
+	},
+	Action: func(context *cli.Context) error {
+		return shutdownAllPods(context.Bool("force"), context.Uint("parallel"))
+	},
+}
+
+// shutdownPod stops the workloads of the specified pod and deletes its
+// resources. With force set, a failure to stop the pod cleanly (for
+// example because the agent is unresponsive) does not prevent the
+// deletion.
+func shutdownPod(podID string, force bool) error {
+	if _, err := vci.StopPod(podID); err != nil {
+		if !force {
+			return err
+		}
+
+		ccLog.WithError(err).WithField("pod", podID).Warn("failed to stop pod cleanly, deleting anyway")
+	}
+
+	if _, err := vci.DeletePod(podID); err != nil {
+		return err
+	}
+
+	ccLog.WithField("pod", podID).Info("pod shut down")
+
+	return nil
+}
+
+// shutdownAllPods shuts down every pod known to the runtime, handling
+// up to parallel pods concurrently. All pods are attempted even if
+// some fail; the error reports the number of failures.
+func shutdownAllPods(force bool, parallel uint) error {
+	podStatusList, err := vci.ListPod()
+	if err != nil {
+		return err
+	}
+
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		failures int
+	)
+
+	sem := make(chan struct{}, parallel)
+
+	for _, podStatus := range podStatusList {
+		wg.Add(1)
+
+		go func(podID string) {
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() {
+				<-sem
+			}()
+
+			if err := shutdownPod(podID, force); err != nil {
+				ccLog.WithError(err).WithField("pod", podID).Error("failed to shut down pod")
+
+				mutex.Lock()
+				failures++
+				mutex.Unlock()
+			}
+		}(podStatus.ID)
+	}
+
+	wg.Wait()
+
+	if failures != 0 {
+		return fmt.Errorf("failed to shut down %d of %d pods", failures, len(podStatusList))
+	}
+
+	return nil
+}
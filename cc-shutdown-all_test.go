@@ -0,0 +1,129 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/vcmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownAllPodsListFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
+		return nil, errors.New("cannot list pods")
+	}
+
+	defer func() {
+		testingImpl.ListPodFunc = nil
+	}()
+
+	err := shutdownAllPods(false, 1)
+	assert.Error(err)
+}
+
+func TestShutdownAllPods(t *testing.T) {
+	assert := assert.New(t)
+
+	podCount := 5
+
+	var podStatusList []vc.PodStatus
+	for i := 0; i < podCount; i++ {
+		podStatusList = append(podStatusList, vc.PodStatus{
+			ID: fmt.Sprintf("pod-%d", i),
+		})
+	}
+
+	var (
+		mutex   sync.Mutex
+		stopped []string
+		deleted []string
+	)
+
+	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
+		return podStatusList, nil
+	}
+
+	testingImpl.StopPodFunc = func(podID string) (vc.VCPod, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		stopped = append(stopped, podID)
+		return &vcmock.Pod{MockID: podID}, nil
+	}
+
+	testingImpl.DeletePodFunc = func(podID string) (vc.VCPod, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		deleted = append(deleted, podID)
+		return &vcmock.Pod{MockID: podID}, nil
+	}
+
+	defer func() {
+		testingImpl.ListPodFunc = nil
+		testingImpl.StopPodFunc = nil
+		testingImpl.DeletePodFunc = nil
+	}()
+
+	err := shutdownAllPods(false, 3)
+	assert.NoError(err)
+	assert.Len(stopped, podCount)
+	assert.Len(deleted, podCount)
+}
+
+func TestShutdownAllPodsStopFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mutex   sync.Mutex
+		deleted []string
+	)
+
+	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
+		return []vc.PodStatus{{ID: testPodID}}, nil
+	}
+
+	testingImpl.StopPodFunc = func(podID string) (vc.VCPod, error) {
+		return nil, errors.New("agent is wedged")
+	}
+
+	testingImpl.DeletePodFunc = func(podID string) (vc.VCPod, error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		deleted = append(deleted, podID)
+		return &vcmock.Pod{MockID: podID}, nil
+	}
+
+	defer func() {
+		testingImpl.ListPodFunc = nil
+		testingImpl.StopPodFunc = nil
+		testingImpl.DeletePodFunc = nil
+	}()
+
+	// without force the pod must not be deleted
+	err := shutdownAllPods(false, 1)
+	assert.Error(err)
+	assert.Empty(deleted)
+
+	// with force the pod is deleted despite the stop failure
+	err = shutdownAllPods(true, 1)
+	assert.NoError(err)
+	assert.Equal([]string{testPodID}, deleted)
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/urfave/cli"
+)
+
+// validation issue severities: errors describe features the workload
+// relies on that this runtime cannot provide, warnings describe
+// features that are silently degraded or ignored.
+const (
+	issueWarning = "WARNING"
+	issueError   = "ERROR"
+)
+
+// validationIssue describes a single spec feature this runtime does
+// not (fully) support.
+type validationIssue struct {
+	level   string
+	message string
+}
+
+// validateSpec checks the OCI spec for features unsupported or only
+// partially supported by this runtime, where workloads run inside a
+// VM rather than a host namespace.
+func validateSpec(ociSpec oci.CompatOCISpec) []validationIssue {
+	var issues []validationIssue
+
+	warn := func(format string, args ...interface{}) {
+		issues = append(issues, validationIssue{issueWarning, fmt.Sprintf(format, args...)})
+	}
+
+	errorf := func(format string, args ...interface{}) {
+		issues = append(issues, validationIssue{issueError, fmt.Sprintf(format, args...)})
+	}
+
+	for _, mount := range ociSpec.Mounts {
+		for _, option := range mount.Options {
+			switch option {
+			case "shared", "rshared":
+				errorf("mount %v: %q propagation cannot cross the VM boundary", mount.Destination, option)
+			case "slave", "rslave":
+				warn("mount %v: %q propagation only applies at pod creation time", mount.Destination, option)
+			}
+		}
+	}
+
+	if ociSpec.Linux != nil {
+		if propagation := ociSpec.Linux.RootfsPropagation; propagation == "shared" || propagation == "rshared" {
+			errorf("rootfs propagation %q cannot cross the VM boundary", propagation)
+		}
+
+		if ociSpec.Linux.Resources != nil {
+			for _, device := range ociSpec.Linux.Resources.Devices {
+				// the default "deny all" rule is part of every spec
+				if !device.Allow && device.Type == "a" {
+					continue
+				}
+
+				warn("device cgroup rules are not enforced inside the guest")
+				break
+			}
+
+		}
+
+		if ociSpec.Linux.IntelRdt != nil {
+			errorf("Intel RDT resource constraints are not supported")
+		}
+
+		if ociSpec.Linux.Seccomp != nil {
+			warn("seccomp profiles are not applied inside the guest")
+		}
+	}
+
+	if ociSpec.Process != nil {
+		if ociSpec.Process.ApparmorProfile != "" {
+			warn("AppArmor profile %q is not applied inside the guest", ociSpec.Process.ApparmorProfile)
+		}
+
+		if ociSpec.Process.SelinuxLabel != "" {
+			warn("SELinux label %q is not applied inside the guest", ociSpec.Process.SelinuxLabel)
+		}
+
+		if unsupported := unsupportedRlimits(ociSpec); len(unsupported) != 0 {
+			warn("rlimits %v are not applied inside the guest", unsupported)
+		}
+	}
+
+	return issues
+}
+
+var ccValidateCLICommand = cli.Command{
+	Name:      validateCmd,
+	Usage:     "validate an OCI bundle for use with " + project,
+	ArgsUsage: `[<bundle>] (defaults to the current directory)`,
+	Description: `Checks the ` + specConfig + ` of the specified bundle for features that
+   are unsupported or only partially supported by ` + project + `. The
+   command exits non-zero if the workload depends on an unsupported
+   feature; warnings alone do not affect the exit code.`,
+	Action: func(context *cli.Context) error {
+		bundlePath := context.Args().First()
+		if bundlePath == "" {
+			bundlePath = "."
+		}
+
+		ociSpec, err := oci.ParseConfigJSON(bundlePath)
+		if err != nil {
+			return err
+		}
+
+		issues := validateSpec(ociSpec)
+
+		errors := 0
+
+		for _, issue := range issues {
+			fmt.Fprintf(defaultOutputFile, "%s: %s\n", issue.level, issue.message)
+
+			if issue.level == issueError {
+				errors++
+			}
+		}
+
+		if errors != 0 {
+			return fmt.Errorf("bundle depends on %d unsupported %s",
+				errors, pluralize("feature", errors))
+		}
+
+		fmt.Fprintf(defaultOutputFile, "Bundle can run under %s\n", project)
+
+		return nil
+	},
+}
+
+// pluralize naively pluralizes the specified noun according to count.
+func pluralize(noun string, count int) string {
+	if count == 1 {
+		return noun
+	}
+
+	if strings.HasSuffix(noun, "s") {
+		return noun + "es"
+	}
+
+	return noun + "s"
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func countIssues(issues []validationIssue, level string) int {
+	count := 0
+
+	for _, issue := range issues {
+		if issue.level == level {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestValidateSpecClean(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Linux = &specs.Linux{
+		Resources: &specs.LinuxResources{
+			Devices: []specs.LinuxDeviceCgroup{
+				// the default "deny all" rule must not be reported
+				{Allow: false, Type: "a"},
+			},
+		},
+	}
+
+	assert.Empty(validateSpec(ociSpec))
+}
+
+func TestValidateSpecMountPropagation(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Mounts = []specs.Mount{
+		{
+			Destination: "/mnt/shared",
+			Options:     []string{"rbind", "rshared"},
+		},
+		{
+			Destination: "/mnt/slave",
+			Options:     []string{"rbind", "rslave"},
+		},
+	}
+
+	issues := validateSpec(ociSpec)
+	assert.Equal(1, countIssues(issues, issueError))
+	assert.Equal(1, countIssues(issues, issueWarning))
+}
+
+func TestValidateSpecUnsupportedFeatures(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Process = &oci.CompatOCIProcess{}
+	ociSpec.Process.ApparmorProfile = "docker-default"
+	ociSpec.Process.SelinuxLabel = "system_u:system_r:svirt_lxc_net_t:s0"
+	ociSpec.Linux = &specs.Linux{
+		RootfsPropagation: "rshared",
+		IntelRdt:          &specs.LinuxIntelRdt{},
+		Seccomp:           &specs.LinuxSeccomp{},
+		Resources: &specs.LinuxResources{
+			Devices: []specs.LinuxDeviceCgroup{
+				{Allow: true, Type: "c"},
+			},
+		},
+	}
+
+	issues := validateSpec(ociSpec)
+
+	// rootfs propagation, Intel RDT
+	assert.Equal(2, countIssues(issues, issueError))
+
+	// AppArmor, SELinux, seccomp, device cgroup rules
+	assert.Equal(4, countIssues(issues, issueWarning))
+}
+
+func TestPluralize(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("feature", pluralize("feature", 1))
+	assert.Equal("features", pluralize("feature", 2))
+	assert.Equal("classes", pluralize("class", 0))
+}
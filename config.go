@@ -20,6 +20,8 @@ import (
 	"io/ioutil"
 	goruntime "runtime"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	vc "github.com/kata-containers/runtime/virtcontainers"
@@ -65,6 +67,11 @@ const (
 	maxPCIBridges uint32 = 5
 )
 
+// hypervisorEntropySource is the resolved host entropy device backing
+// the virtio-rng device attached to every pod VM. It is stored here
+// since the virtcontainers hypervisor configuration does not carry it.
+var hypervisorEntropySource = defaultEntropySource
+
 type tomlConfig struct {
 	Hypervisor map[string]hypervisor
 	Proxy      map[string]proxy
@@ -76,7 +83,9 @@ type tomlConfig struct {
 type hypervisor struct {
 	Path                  string `toml:"path"`
 	Kernel                string `toml:"kernel"`
+	KernelDigest          string `toml:"kernel_digest"`
 	Image                 string `toml:"image"`
+	ImageDigest           string `toml:"image_digest"`
 	Firmware              string `toml:"firmware"`
 	MachineAccelerators   string `toml:"machine_accelerators"`
 	KernelParams          string `toml:"kernel_params"`
@@ -86,6 +95,7 @@ type hypervisor struct {
 	DefaultBridges        uint32 `toml:"default_bridges"`
 	DisableBlockDeviceUse bool   `toml:"disable_block_device_use"`
 	BlockDeviceDriver     string `toml:"block_device_driver"`
+	EntropySource         string `toml:"entropy_source"`
 	MemPrealloc           bool   `toml:"enable_mem_prealloc"`
 	HugePages             bool   `toml:"enable_hugepages"`
 	Swap                  bool   `toml:"enable_swap"`
@@ -101,6 +111,16 @@ type proxy struct {
 type runtime struct {
 	Debug             bool   `toml:"enable_debug"`
 	InterNetworkModel string `toml:"internetworking_model"`
+	EnableCrashDump   bool   `toml:"enable_crash_dump"`
+	CrashDumpDir      string `toml:"crash_dump_dir"`
+	EnableTimeSync    bool   `toml:"enable_time_sync"`
+	EnableCoreDump    bool   `toml:"enable_core_dump"`
+	CoreDumpDir       string `toml:"core_dump_dir"`
+	EnableNetTuning   bool   `toml:"enable_network_tuning"`
+	IOTuningPreset    string `toml:"io_tuning_preset"`
+	SandboxCgroup     bool   `toml:"enable_sandbox_cgroup"`
+	SandboxMemMB      int64  `toml:"sandbox_cgroup_memory_overhead_mb"`
+	SandboxCPUPct     int64  `toml:"sandbox_cgroup_cpu_overhead_pct"`
 }
 
 type shim struct {
@@ -109,6 +129,10 @@ type shim struct {
 }
 
 type agent struct {
+	Version            string `toml:"version"`
+	RefuseIncompatible bool   `toml:"refuse_incompatible"`
+	PingRetries        int    `toml:"ping_retries"`
+	RequestTimeout     int64  `toml:"request_timeout"`
 }
 
 func (h hypervisor) path() (string, error) {
@@ -230,6 +254,30 @@ func (h hypervisor) blockDeviceDriver() (string, error) {
 	return h.BlockDeviceDriver, nil
 }
 
+func (h hypervisor) entropySource() (string, error) {
+	p := h.EntropySource
+
+	if p == "" {
+		p = defaultEntropySource
+	}
+
+	resolved, err := resolvePath(p)
+	if err != nil {
+		return "", err
+	}
+
+	var stat syscall.Stat_t
+	if err := syscall.Stat(resolved, &stat); err != nil {
+		return "", err
+	}
+
+	if stat.Mode&syscall.S_IFMT != syscall.S_IFCHR {
+		return "", fmt.Errorf("Entropy source %v is not a character device", resolved)
+	}
+
+	return resolved, nil
+}
+
 func (p proxy) path() string {
 	if p.Path == "" {
 		return defaultProxyPath
@@ -286,6 +334,16 @@ func newQemuHypervisorConfig(h hypervisor) (vc.HypervisorConfig, error) {
 		return vc.HypervisorConfig{}, err
 	}
 
+	entropySource, err := h.entropySource()
+	if err != nil {
+		return vc.HypervisorConfig{}, err
+	}
+
+	hypervisorEntropySource = entropySource
+
+	kernelDigest = h.KernelDigest
+	imageDigest = h.ImageDigest
+
 	return vc.HypervisorConfig{
 		HypervisorPath:        hypervisor,
 		KernelPath:            kernel,
@@ -348,7 +406,7 @@ func updateRuntimeConfig(configPath string, tomlConf tomlConfig, config *oci.Run
 		}
 	}
 
-	for k := range tomlConf.Agent {
+	for k, agent := range tomlConf.Agent {
 		switch k {
 		case hyperstartAgentTableType:
 			config.AgentType = hyperstartAgentTableType
@@ -359,6 +417,20 @@ func updateRuntimeConfig(configPath string, tomlConf tomlConfig, config *oci.Run
 			config.AgentConfig = vc.KataAgentConfig{}
 
 		}
+
+		if agent.Version != "" {
+			agentVersion = agent.Version
+		}
+
+		refuseIncompatibleAgent = agent.RefuseIncompatible
+
+		if agent.PingRetries > 0 {
+			agentPingRetries = agent.PingRetries
+		}
+
+		if agent.RequestTimeout > 0 {
+			agentRequestTimeout = time.Duration(agent.RequestTimeout) * time.Second
+		}
 	}
 
 	for k, shim := range tomlConf.Shim {
@@ -454,6 +526,37 @@ func loadConfiguration(configPath string, ignoreLogging bool) (resolvedConfigPat
 		ccLog.Logger.Level = originalLoggerLevel
 	}
 
+	enableCrashDump = tomlConf.Runtime.EnableCrashDump
+	if tomlConf.Runtime.CrashDumpDir != "" {
+		crashDumpDir = tomlConf.Runtime.CrashDumpDir
+	}
+
+	enableTimeSync = tomlConf.Runtime.EnableTimeSync
+
+	enableCoreDump = tomlConf.Runtime.EnableCoreDump
+	if tomlConf.Runtime.CoreDumpDir != "" {
+		coreDumpDir = tomlConf.Runtime.CoreDumpDir
+	}
+
+	enableNetworkTuning = tomlConf.Runtime.EnableNetTuning
+
+	enableSandboxCgroup = tomlConf.Runtime.SandboxCgroup
+
+	if tomlConf.Runtime.SandboxMemMB > 0 {
+		sandboxMemOverheadMB = tomlConf.Runtime.SandboxMemMB
+	}
+
+	if tomlConf.Runtime.SandboxCPUPct > 0 {
+		sandboxCPUOverheadPct = tomlConf.Runtime.SandboxCPUPct
+	}
+
+	if tomlConf.Runtime.IOTuningPreset != "" {
+		if _, ok := ioTuningPresets[tomlConf.Runtime.IOTuningPreset]; !ok {
+			return "", config, fmt.Errorf("Unknown I/O tuning preset %q", tomlConf.Runtime.IOTuningPreset)
+		}
+	}
+	ioTuningPreset = tomlConf.Runtime.IOTuningPreset
+
 	if tomlConf.Runtime.InterNetworkModel != "" {
 		err = config.InterNetworkModel.SetModel(tomlConf.Runtime.InterNetworkModel)
 		if err != nil {
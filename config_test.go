@@ -793,6 +793,39 @@ func TestHypervisorDefaultsImage(t *testing.T) {
 	assert.Equal(p, testImagePath)
 }
 
+func TestHypervisorDefaultsEntropySource(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	h := hypervisor{}
+	p, err := h.entropySource()
+	assert.NoError(err)
+	assert.Equal(p, defaultEntropySource, "default entropy source wrong")
+
+	// a regular file is not a valid entropy source
+	testFilePath := filepath.Join(tmpdir, "entropy")
+	err = createEmptyFile(testFilePath)
+	assert.NoError(err)
+
+	h.EntropySource = testFilePath
+	_, err = h.entropySource()
+	assert.Error(err)
+
+	// any character device is accepted
+	h.EntropySource = "/dev/zero"
+	p, err = h.entropySource()
+	assert.NoError(err)
+	assert.Equal(p, "/dev/zero")
+
+	// a non-existent path is rejected
+	h.EntropySource = filepath.Join(tmpdir, "does-not-exist")
+	_, err = h.entropySource()
+	assert.Error(err)
+}
+
 func TestProxyDefaults(t *testing.T) {
 	p := proxy{}
 
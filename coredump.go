@@ -0,0 +1,106 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// defaultCoreDumpDir is the host directory workload core dumps are
+// collected below unless the configuration specifies otherwise.
+const defaultCoreDumpDir = "/var/crash/" + name + "/cores"
+
+// guestCorePattern is the core pattern set in the guest. It is
+// deliberately relative: the guest kernel then writes cores into the
+// working directory of the crashing process, which lives on the
+// 9p-shared container rootfs and is therefore visible on the host.
+const guestCorePattern = "core.%e.%p"
+
+// core dump settings from the [runtime] section of the configuration
+// file.
+var (
+	enableCoreDump = false
+	coreDumpDir    = defaultCoreDumpDir
+)
+
+// coreDumpGuestSysctls returns the guest sysctls used when core dump
+// capture is enabled.
+func coreDumpGuestSysctls() []guestSysctl {
+	return []guestSysctl{
+		{
+			key:   "kernel.core_pattern",
+			value: guestCorePattern,
+		},
+	}
+}
+
+// collectCoreDumps copies the core dumps found on the container rootfs
+// into a per-container directory below coreDumpDir and returns the
+// number of cores collected. Individual cores are truncated at
+// maxCrashFileSize bytes so a huge core cannot fill the host disk.
+func collectCoreDumps(containerID, rootFs string) (int, error) {
+	if rootFs == "" || !fileExists(rootFs) {
+		return 0, nil
+	}
+
+	collected := 0
+
+	err := filepath.Walk(rootFs, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			// unreadable parts of the rootfs are skipped, not fatal
+			return nil
+		}
+
+		if info.IsDir() || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if !strings.HasPrefix(filepath.Base(path), "core.") {
+			return nil
+		}
+
+		targetDir := filepath.Join(coreDumpDir, containerID)
+
+		if err := os.MkdirAll(targetDir, 0700); err != nil {
+			return err
+		}
+
+		from, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer from.Close()
+
+		to, err := os.OpenFile(filepath.Join(targetDir, filepath.Base(path)),
+			os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer to.Close()
+
+		if _, err := io.CopyN(to, from, maxCrashFileSize); err != nil && err != io.EOF {
+			return err
+		}
+
+		collected++
+
+		return nil
+	})
+
+	return collected, err
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoreDumpGuestSysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	sysctls := coreDumpGuestSysctls()
+	assert.NotEmpty(sysctls)
+
+	assert.Equal("kernel.core_pattern", sysctls[0].key)
+	assert.Equal(guestCorePattern, sysctls[0].value)
+}
+
+func TestCollectCoreDumps(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedCoreDumpDir := coreDumpDir
+
+	defer func() {
+		coreDumpDir = savedCoreDumpDir
+	}()
+
+	coreDumpDir = filepath.Join(tmpdir, "cores")
+
+	// missing rootfs is not an error
+	count, err := collectCoreDumps(testContainerID, filepath.Join(tmpdir, "does-not-exist"))
+	assert.NoError(err)
+	assert.Equal(0, count)
+
+	rootFs := filepath.Join(tmpdir, "rootfs")
+	workDir := filepath.Join(rootFs, "work")
+	assert.NoError(os.MkdirAll(workDir, 0750))
+
+	assert.NoError(createFile(filepath.Join(workDir, "core.app.42"), "core data"))
+	assert.NoError(createFile(filepath.Join(workDir, "data.txt"), "not a core"))
+
+	count, err = collectCoreDumps(testContainerID, rootFs)
+	assert.NoError(err)
+	assert.Equal(1, count)
+
+	collected := filepath.Join(coreDumpDir, testContainerID, "core.app.42")
+	contents, err := getFileContents(collected)
+	assert.NoError(err)
+	assert.Equal("core data", contents)
+
+	// non-core files must not be collected
+	assert.False(fileExists(filepath.Join(coreDumpDir, testContainerID, "data.txt")))
+}
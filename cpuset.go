@@ -0,0 +1,262 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// cpusetAnnotation is the annotation a pod can use to request its VM
+// to be pinned to an exclusive set of host CPUs, in the usual cpuset
+// list format ("0,2-4").
+const cpusetAnnotation = ccAnnotationsPrefix + "cpuset"
+
+// cpuManagerStateFile records which host CPUs are assigned to which
+// pod so that exclusivity can be enforced across pods ("var" to allow
+// tests to modify it).
+var cpuManagerStateFile = "/run/" + name + "/cpusets.json"
+
+// tasksetCmd is the command used to apply the CPU affinity ("var" to
+// allow tests to modify it).
+var tasksetCmd = "taskset"
+
+// parseCPUSet expands a cpuset list string ("0,2-4") into the CPU
+// numbers it covers.
+func parseCPUSet(cpuset string) ([]int, error) {
+	var cpus []int
+
+	for _, chunk := range strings.Split(cpuset, ",") {
+		chunk = strings.TrimSpace(chunk)
+		if chunk == "" {
+			continue
+		}
+
+		bounds := strings.SplitN(chunk, "-", 2)
+
+		first, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("Invalid cpuset %q", cpuset)
+		}
+
+		last := first
+
+		if len(bounds) == 2 {
+			last, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || last < first {
+				return nil, fmt.Errorf("Invalid cpuset %q", cpuset)
+			}
+		}
+
+		for cpu := first; cpu <= last; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+
+	if len(cpus) == 0 {
+		return nil, fmt.Errorf("Empty cpuset %q", cpuset)
+	}
+
+	return cpus, nil
+}
+
+// cpusetsOverlap returns true if the two CPU lists share any CPU.
+func cpusetsOverlap(a, b []int) bool {
+	set := map[int]bool{}
+
+	for _, cpu := range a {
+		set[cpu] = true
+	}
+
+	for _, cpu := range b {
+		if set[cpu] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// withCPUAssignmentsLock runs fn while holding an exclusive flock(2)
+// on a lock file next to the state file, so that concurrent runtime
+// invocations cannot interleave their read-modify-write cycles and
+// hand the same CPUs to two pods.
+func withCPUAssignmentsLock(fn func() error) error {
+	if err := os.MkdirAll(filepath.Dir(cpuManagerStateFile), 0700); err != nil {
+		return err
+	}
+
+	lock, err := os.OpenFile(cpuManagerStateFile+".lock", os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	if err := syscall.Flock(int(lock.Fd()), syscall.LOCK_EX); err != nil {
+		return err
+	}
+	defer syscall.Flock(int(lock.Fd()), syscall.LOCK_UN)
+
+	return fn()
+}
+
+// loadCPUAssignments reads the recorded pod to cpuset assignments. A
+// missing state file simply means no assignments exist yet.
+func loadCPUAssignments() (map[string]string, error) {
+	assignments := map[string]string{}
+
+	contents, err := getFileContents(cpuManagerStateFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return assignments, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(contents), &assignments); err != nil {
+		return nil, err
+	}
+
+	return assignments, nil
+}
+
+// saveCPUAssignments writes the pod to cpuset assignments back to the
+// state file.
+func saveCPUAssignments(assignments map[string]string) error {
+	if err := os.MkdirAll(filepath.Dir(cpuManagerStateFile), 0700); err != nil {
+		return err
+	}
+
+	bytes, err := json.Marshal(assignments)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(cpuManagerStateFile, string(bytes), 0600)
+}
+
+// reserveCPUSet records the specified cpuset as owned by the pod,
+// failing if any of its CPUs is already assigned to another pod:
+// dedicated CPUs that are silently shared are worse than an error. The
+// check and the update happen under the assignments lock so that two
+// pods created concurrently cannot both reserve the same CPUs.
+func reserveCPUSet(podID, cpuset string) error {
+	cpus, err := parseCPUSet(cpuset)
+	if err != nil {
+		return err
+	}
+
+	return withCPUAssignmentsLock(func() error {
+		assignments, err := loadCPUAssignments()
+		if err != nil {
+			return err
+		}
+
+		for otherPod, otherSet := range assignments {
+			if otherPod == podID {
+				continue
+			}
+
+			otherCPUs, err := parseCPUSet(otherSet)
+			if err != nil {
+				continue
+			}
+
+			if cpusetsOverlap(cpus, otherCPUs) {
+				return fmt.Errorf("cpuset %v overlaps with the CPUs assigned to pod %v", cpuset, otherPod)
+			}
+		}
+
+		assignments[podID] = cpuset
+
+		return saveCPUAssignments(assignments)
+	})
+}
+
+// releaseCPUSet drops the cpuset assignment of the specified pod, if
+// any.
+func releaseCPUSet(podID string) error {
+	return withCPUAssignmentsLock(func() error {
+		assignments, err := loadCPUAssignments()
+		if err != nil {
+			return err
+		}
+
+		if _, ok := assignments[podID]; !ok {
+			return nil
+		}
+
+		// note: the builtin delete() is shadowed by the delete CLI handler
+		// in this package, so filter the map instead.
+		remaining := map[string]string{}
+		for pod, cpuset := range assignments {
+			if pod != podID {
+				remaining[pod] = cpuset
+			}
+		}
+
+		return saveCPUAssignments(remaining)
+	})
+}
+
+// pinPodCPUs pins the pod VM (all its threads, vCPU threads included)
+// to the host CPUs requested via the cpuset annotation. Failing to pin
+// fails the pod creation: latency-critical workloads must not run on
+// shared cores unnoticed.
+func pinPodCPUs(ociSpec oci.CompatOCISpec, podID string) error {
+	cpuset, ok := ociSpec.Annotations[cpusetAnnotation]
+	if !ok || cpuset == "" {
+		return nil
+	}
+
+	if err := reserveCPUSet(podID, cpuset); err != nil {
+		return err
+	}
+
+	// past this point the reservation must not outlive a failed pin,
+	// or the CPUs would stay blocked for pods that never got them
+	pid := findVMPid(podID)
+	if pid == 0 {
+		dropCPUSetReservation(podID)
+		return fmt.Errorf("cannot pin pod %v: VM process not found", podID)
+	}
+
+	output, err := exec.Command(tasksetCmd, "-a", "-p", "-c", cpuset, strconv.Itoa(pid)).CombinedOutput()
+	if err != nil {
+		dropCPUSetReservation(podID)
+		return fmt.Errorf("failed to pin pod %v to cpuset %v: %v: %s", podID, cpuset, err, output)
+	}
+
+	ccLog.WithField("cpuset", cpuset).Info("pod VM pinned to dedicated CPUs")
+
+	return nil
+}
+
+// dropCPUSetReservation releases the cpuset reserved for a pod whose
+// pinning failed. The pin error is what the caller reports, so a
+// failure to release only warns.
+func dropCPUSetReservation(podID string) {
+	if err := releaseCPUSet(podID); err != nil {
+		ccLog.WithError(err).WithField("pod", podID).Warn("cannot release cpuset reservation")
+	}
+}
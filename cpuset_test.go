@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUSet(t *testing.T) {
+	assert := assert.New(t)
+
+	cpus, err := parseCPUSet("0")
+	assert.NoError(err)
+	assert.Equal([]int{0}, cpus)
+
+	cpus, err = parseCPUSet("0,2-4, 7")
+	assert.NoError(err)
+	assert.Equal([]int{0, 2, 3, 4, 7}, cpus)
+
+	for _, invalid := range []string{"", ",", "a", "-1", "4-2", "1-"} {
+		_, err = parseCPUSet(invalid)
+		assert.Error(err, "cpuset %q", invalid)
+	}
+}
+
+func TestCPUSetsOverlap(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(cpusetsOverlap([]int{0, 1}, []int{1, 2}))
+	assert.False(cpusetsOverlap([]int{0, 1}, []int{2, 3}))
+	assert.False(cpusetsOverlap(nil, []int{0}))
+}
+
+func TestReserveAndReleaseCPUSet(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedCPUManagerStateFile := cpuManagerStateFile
+
+	defer func() {
+		cpuManagerStateFile = savedCPUManagerStateFile
+	}()
+
+	cpuManagerStateFile = filepath.Join(tmpdir, "cpusets.json")
+
+	// no state file yet
+	assignments, err := loadCPUAssignments()
+	assert.NoError(err)
+	assert.Empty(assignments)
+
+	assert.NoError(reserveCPUSet("pod1", "2-3"))
+
+	// overlapping set for another pod must be refused
+	assert.Error(reserveCPUSet("pod2", "3-4"))
+
+	// disjoint set is fine
+	assert.NoError(reserveCPUSet("pod2", "4-5"))
+
+	// a pod may re-reserve (overlapping) its own set
+	assert.NoError(reserveCPUSet("pod1", "2"))
+
+	assignments, err = loadCPUAssignments()
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"pod1": "2",
+		"pod2": "4-5",
+	}, assignments)
+
+	assert.NoError(releaseCPUSet("pod1"))
+
+	// releasing an unknown pod is not an error
+	assert.NoError(releaseCPUSet("pod1"))
+
+	assignments, err = loadCPUAssignments()
+	assert.NoError(err)
+	assert.Equal(map[string]string{
+		"pod2": "4-5",
+	}, assignments)
+
+	// invalid cpuset is refused before touching the state
+	assert.Error(reserveCPUSet("pod3", "not-a-cpuset"))
+}
+
+func TestPinPodCPUsFailureReleasesReservation(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedCPUManagerStateFile := cpuManagerStateFile
+	savedProcDir := procDir
+
+	defer func() {
+		cpuManagerStateFile = savedCPUManagerStateFile
+		procDir = savedProcDir
+	}()
+
+	cpuManagerStateFile = filepath.Join(tmpdir, "cpusets.json")
+
+	// an empty proc directory means the VM process cannot be found
+	procDir = filepath.Join(tmpdir, "proc")
+	assert.NoError(os.MkdirAll(procDir, testDirMode))
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Annotations = map[string]string{
+		cpusetAnnotation: "2-3",
+	}
+
+	assert.Error(pinPodCPUs(ociSpec, testPodID))
+
+	// the failed pin must not leave the CPUs reserved
+	assignments, err := loadCPUAssignments()
+	assert.NoError(err)
+	assert.Empty(assignments)
+}
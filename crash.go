@@ -0,0 +1,156 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+)
+
+// defaultCrashDumpDir is the directory crash dump bundles are created
+// below unless the configuration specifies otherwise.
+const defaultCrashDumpDir = "/var/crash/" + name
+
+// maxCrashFileSize caps the size of any single file copied into a
+// crash dump bundle so that a huge guest memory image cannot fill the
+// host disk.
+const maxCrashFileSize = int64(16 * 1024 * 1024)
+
+// crash dump settings from the [runtime] section of the configuration
+// file.
+var (
+	enableCrashDump = false
+	crashDumpDir    = defaultCrashDumpDir
+)
+
+// pod storage paths used by virtcontainers ("var" to allow tests to
+// modify them).
+var (
+	podRunStoragePath    = "/run/virtcontainers/pods"
+	podConfigStoragePath = "/var/lib/virtcontainers/pods"
+)
+
+// crashDumpKernelParams returns the extra guest kernel parameters used
+// when crash dump collection is enabled: they force a panicking (or
+// oopsing) guest kernel to terminate the VM immediately so that the
+// runtime state it leaves behind can be collected, rather than leaving
+// a wedged VM around.
+func crashDumpKernelParams() []vc.Param {
+	return []vc.Param{
+		{
+			Key:   "panic",
+			Value: "1",
+		},
+		{
+			Key:   "oops",
+			Value: "panic",
+		},
+	}
+}
+
+// collectCrashDump gathers post-mortem data for the specified pod into
+// a new bundle directory below crashDumpDir and returns its path. The
+// bundle contains the runtime view of the pod status along with the
+// on-disk pod configuration and runtime state maintained by
+// virtcontainers.
+func collectCrashDump(podID string) (string, error) {
+	if podID == "" {
+		return "", fmt.Errorf("Missing pod ID")
+	}
+
+	bundleDir := filepath.Join(crashDumpDir,
+		fmt.Sprintf("%s-%s", podID, time.Now().UTC().Format("20060102-150405.000")))
+
+	if err := os.MkdirAll(bundleDir, 0700); err != nil {
+		return "", err
+	}
+
+	// The pod status is collected best effort: the pod may be in too
+	// bad a state for virtcontainers to report on it.
+	if podStatus, err := vci.StatusPod(podID); err == nil {
+		if bytes, err := json.MarshalIndent(podStatus, "", "  "); err == nil {
+			writeFile(filepath.Join(bundleDir, "status.json"), string(bytes), 0600)
+		}
+	}
+
+	dirs := map[string]string{
+		"run":   filepath.Join(podRunStoragePath, podID),
+		"state": filepath.Join(podConfigStoragePath, podID),
+	}
+
+	for subdir, dir := range dirs {
+		if !fileExists(dir) {
+			continue
+		}
+
+		if err := copyCrashFiles(dir, filepath.Join(bundleDir, subdir)); err != nil {
+			return "", err
+		}
+	}
+
+	return bundleDir, nil
+}
+
+// copyCrashFiles copies the regular files below src into dst,
+// preserving the directory layout. Individual files are truncated at
+// maxCrashFileSize bytes.
+func copyCrashFiles(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		target := filepath.Join(dst, relPath)
+
+		if info.IsDir() {
+			return os.MkdirAll(target, 0700)
+		}
+
+		if !info.Mode().IsRegular() {
+			// sockets, fifos, etc cannot be usefully copied
+			return nil
+		}
+
+		from, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer from.Close()
+
+		to, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer to.Close()
+
+		_, err = io.CopyN(to, from, maxCrashFileSize)
+		if err == io.EOF {
+			err = nil
+		}
+
+		return err
+	})
+}
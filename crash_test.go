@@ -0,0 +1,94 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrashDumpKernelParams(t *testing.T) {
+	assert := assert.New(t)
+
+	params := crashDumpKernelParams()
+	assert.NotEmpty(params)
+
+	for _, p := range params {
+		assert.NotEmpty(p.Key)
+		assert.NotEmpty(p.Value)
+	}
+}
+
+func TestCollectCrashDump(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := collectCrashDump("")
+	assert.Error(err)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedCrashDumpDir := crashDumpDir
+	savedPodRunStoragePath := podRunStoragePath
+	savedPodConfigStoragePath := podConfigStoragePath
+
+	defer func() {
+		crashDumpDir = savedCrashDumpDir
+		podRunStoragePath = savedPodRunStoragePath
+		podConfigStoragePath = savedPodConfigStoragePath
+	}()
+
+	crashDumpDir = filepath.Join(tmpdir, "crash")
+	podRunStoragePath = filepath.Join(tmpdir, "run")
+	podConfigStoragePath = filepath.Join(tmpdir, "lib")
+
+	podID := testPodID
+
+	// fake pod state files
+	for _, dir := range []string{podRunStoragePath, podConfigStoragePath} {
+		podDir := filepath.Join(dir, podID)
+		err = os.MkdirAll(podDir, testDirMode)
+		assert.NoError(err)
+
+		err = createFile(filepath.Join(podDir, "state.json"), `{"state":"ready"}`)
+		assert.NoError(err)
+	}
+
+	testingImpl.StatusPodFunc = func(podID string) (vc.PodStatus, error) {
+		return vc.PodStatus{ID: podID}, nil
+	}
+
+	defer func() {
+		testingImpl.StatusPodFunc = nil
+	}()
+
+	bundleDir, err := collectCrashDump(podID)
+	assert.NoError(err)
+	assert.True(fileExists(bundleDir))
+
+	for _, file := range []string{
+		"status.json",
+		filepath.Join("run", "state.json"),
+		filepath.Join("state", "state.json"),
+	} {
+		assert.True(fileExists(filepath.Join(bundleDir, file)), "expected %v in bundle", file)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 
 	vc "github.com/kata-containers/runtime/virtcontainers"
 	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
@@ -101,7 +102,7 @@ func create(containerID, bundlePath, console, pidFilePath string, detach bool,
 
 	ociSpec, err := oci.ParseConfigJSON(bundlePath)
 	if err != nil {
-		return err
+		return specError(err)
 	}
 
 	containerType, err := ociSpec.ContainerType()
@@ -111,6 +112,34 @@ func create(containerID, bundlePath, console, pidFilePath string, detach bool,
 
 	disableOutput := noNeedForOutput(detach, ociSpec.Process.Terminal)
 
+	if isInfraContainer(ociSpec) {
+		// The pause workload only exists to hold the pod, which the VM
+		// already does here. It still has to run until the agent can
+		// elide it, but it is excluded from workload accounting.
+		ccLog.WithField("container", containerID).Debug("detected CRI infra container")
+	} else {
+		// Best effort check: log the I/O path the container rootfs
+		// will use so that unexpected 9p fallbacks can be diagnosed.
+		checkRootFsBlockBacking(ociSpec, runtimeConfig, bundlePath)
+	}
+
+	// Devices allocated out-of-band (for example by Kubernetes device
+	// plugins) are described via annotations rather than the OCI spec.
+	if err := addDevicesFromAnnotations(&ociSpec); err != nil {
+		return err
+	}
+
+	// Environment variables and secrets referenced by annotations are
+	// merged into the in-memory spec only, so they never land in the
+	// config.json left in the bundle.
+	if err := injectEnvFromAnnotations(&ociSpec); err != nil {
+		return specError(err)
+	}
+
+	if err := injectSecretsFromAnnotations(&ociSpec, containerID); err != nil {
+		return specError(err)
+	}
+
 	var process vc.Process
 
 	switch containerType {
@@ -119,11 +148,64 @@ func create(containerID, bundlePath, console, pidFilePath string, detach bool,
 		if err != nil {
 			return err
 		}
+
+		// The entropy device keeps the guest random number generator
+		// fed; a pod without it still runs, so failing to attach it
+		// only warns.
+		if err := attachEntropyDevice(containerID); err != nil {
+			ccLog.WithError(err).WithField("pod", containerID).Warn("cannot attach entropy device")
+		}
+
+		// CPU pinning has to happen once the VM process exists and
+		// before the workload runs anything latency-critical.
+		if err := pinPodCPUs(ociSpec, containerID); err != nil {
+			return err
+		}
+
+		// With sandbox cgroups enabled, the host processes backing the
+		// pod are gathered in a per-pod cgroup so the VM overhead is
+		// visible to host resource accounting.
+		if enableSandboxCgroup {
+			if err := createPodCgroup(containerID, process.Pid); err != nil {
+				return err
+			}
+
+			if err := growPodCgroup(ociSpec, containerID); err != nil {
+				return err
+			}
+		}
+
+		// Network QoS is a per-pod property applied to the interface
+		// in the pod network namespace.
+		if err := applyNetworkQoS(ociSpec); err != nil {
+			return err
+		}
+
+		applyNetworkTuning(ociSpec)
 	case vc.PodContainer:
+		podID, err := ociSpec.PodID()
+		if err != nil {
+			return err
+		}
+
+		// An orchestrator restarting a crashed container re-creates it
+		// with the same ID in the same pod: clear the exited incarnation
+		// so the pod VM is reused instead of rebooted.
+		if _, err := handleContainerRestart(podID, containerID); err != nil {
+			return err
+		}
+
 		process, err = createContainer(ociSpec, containerID, bundlePath, console, disableOutput)
 		if err != nil {
 			return err
 		}
+
+		// Each container contributes its limits to the pod cgroup.
+		if enableSandboxCgroup {
+			if err := growPodCgroup(ociSpec, podID); err != nil {
+				return err
+			}
+		}
 	}
 
 	// config.json provides a cgroups path that has to be used to create "tasks"
@@ -152,6 +234,83 @@ func create(containerID, bundlePath, console, pidFilePath string, detach bool,
 	return createPIDFile(pidFilePath, process.Pid)
 }
 
+// sysDevBlockDir is the directory exposing details of all host block
+// devices ("var" to allow tests to modify it).
+var sysDevBlockDir = "/sys/dev/block"
+
+// rootFsOnDeviceMapper returns true if the filesystem holding the
+// specified container rootfs is backed by a device-mapper device,
+// which is the case when docker is configured to use the devicemapper
+// storage driver.
+func rootFsOnDeviceMapper(rootFs string) (bool, error) {
+	if rootFs == "" {
+		return false, fmt.Errorf("Missing rootfs path")
+	}
+
+	stat := syscall.Stat_t{}
+	if err := syscall.Stat(rootFs, &stat); err != nil {
+		return false, err
+	}
+
+	// stat.Dev refers to the device holding the filesystem the rootfs
+	// lives on.
+	major := int((stat.Dev >> 8) & 0xfff)
+	minor := int((stat.Dev & 0xff) | ((stat.Dev >> 12) & 0xfff00))
+
+	// Only device-mapper devices expose a "dm" directory in sysfs.
+	dmPath := filepath.Join(sysDevBlockDir, fmt.Sprintf("%d:%d", major, minor), "dm")
+
+	_, err := os.Stat(dmPath)
+	if err == nil {
+		return true, nil
+	}
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// checkRootFsBlockBacking inspects the backing store of the container
+// rootfs and logs the I/O path the workload will use: a device-mapper
+// backed rootfs is attached to the VM as a block device (unless the
+// configuration disallows it), anything else is shared via 9p.
+func checkRootFsBlockBacking(ociSpec oci.CompatOCISpec, runtimeConfig oci.RuntimeConfig, bundlePath string) {
+	if ociSpec.Root == nil {
+		return
+	}
+
+	rootFs := ociSpec.Root.Path
+	if rootFs != "" && !filepath.IsAbs(rootFs) {
+		rootFs = filepath.Join(bundlePath, rootFs)
+	}
+
+	isDM, err := rootFsOnDeviceMapper(rootFs)
+	if err != nil {
+		ccLog.WithError(err).WithField("rootfs", rootFs).Debug("cannot detect rootfs backing store")
+		return
+	}
+
+	fields := logrus.Fields{
+		"rootfs":                   rootFs,
+		"block-device-driver":      runtimeConfig.HypervisorConfig.BlockDeviceDriver,
+		"disable-block-device-use": runtimeConfig.HypervisorConfig.DisableBlockDeviceUse,
+	}
+
+	if !isDM {
+		ccLog.WithFields(fields).Debug("rootfs not backed by a block device, sharing via 9p")
+		return
+	}
+
+	if runtimeConfig.HypervisorConfig.DisableBlockDeviceUse {
+		ccLog.WithFields(fields).Warn("device-mapper rootfs shared via 9p as block device use is disabled")
+		return
+	}
+
+	ccLog.WithFields(fields).Info("device-mapper rootfs will be attached as a block device")
+}
+
 func getKernelParams(containerID string) []vc.Param {
 	return []vc.Param{
 		{
@@ -215,11 +374,41 @@ func setKernelParams(containerID string, runtimeConfig *oci.RuntimeConfig) error
 func createPod(ociSpec oci.CompatOCISpec, runtimeConfig oci.RuntimeConfig,
 	containerID, bundlePath, console string, disableOutput bool) (vc.Process, error) {
 
+	if err := verifyGuestArtifacts(runtimeConfig); err != nil {
+		return vc.Process{}, err
+	}
+
+	// Incompatible image/agent combinations fail with opaque hangs once
+	// the VM is up, so refuse them (when configured) before booting.
+	if err := checkAgentCompatibility(); err != nil {
+		return vc.Process{}, err
+	}
+
 	err := setKernelParams(containerID, &runtimeConfig)
 	if err != nil {
 		return vc.Process{}, err
 	}
 
+	if err := addGuestConfigParams(ociSpec, &runtimeConfig); err != nil {
+		return vc.Process{}, err
+	}
+
+	if enableCrashDump {
+		for _, p := range crashDumpKernelParams() {
+			if err := runtimeConfig.AddKernelParam(p); err != nil {
+				return vc.Process{}, err
+			}
+		}
+	}
+
+	if enableTimeSync {
+		for _, p := range timeSyncKernelParams() {
+			if err := runtimeConfig.AddKernelParam(p); err != nil {
+				return vc.Process{}, err
+			}
+		}
+	}
+
 	podConfig, err := oci.PodConfig(ociSpec, runtimeConfig, bundlePath, containerID, console, disableOutput)
 	if err != nil {
 		return vc.Process{}, err
@@ -227,7 +416,7 @@ func createPod(ociSpec oci.CompatOCISpec, runtimeConfig oci.RuntimeConfig,
 
 	pod, err := vci.CreatePod(podConfig)
 	if err != nil {
-		return vc.Process{}, err
+		return vc.Process{}, hypervisorError(err)
 	}
 
 	containers := pod.GetAllContainers()
@@ -251,7 +440,19 @@ func createContainer(ociSpec oci.CompatOCISpec, containerID, bundlePath,
 		return vc.Process{}, err
 	}
 
-	_, c, err := vci.CreateContainer(podID, contConfig)
+	// The pod VM is already up, so its agent must be answering before
+	// a container create is sent down the channel.
+	if err := pingAgent(podID); err != nil {
+		return vc.Process{}, err
+	}
+
+	var c vc.VCContainer
+
+	err = withAgentTimeout("create container", func() error {
+		var createErr error
+		_, c, createErr = vci.CreateContainer(podID, contConfig)
+		return createErr
+	})
 	if err != nil {
 		return vc.Process{}, err
 	}
@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"testing"
 
 	vc "github.com/kata-containers/runtime/virtcontainers"
@@ -1195,3 +1196,53 @@ func TestSetKernelParamsUserOptionTakesPriority(t *testing.T) {
 	assert.Equal(ipValue, ip)
 
 }
+
+func TestRootFsOnDeviceMapper(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := rootFsOnDeviceMapper("")
+	assert.Error(err)
+
+	_, err = rootFsOnDeviceMapper("/this/path/does/not/exist")
+	assert.Error(err)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	rootFs := filepath.Join(tmpdir, "rootfs")
+	err = os.MkdirAll(rootFs, testDirMode)
+	assert.NoError(err)
+
+	savedSysDevBlockDir := sysDevBlockDir
+
+	defer func() {
+		sysDevBlockDir = savedSysDevBlockDir
+	}()
+
+	// An empty sysfs directory means the backing device cannot be a
+	// device-mapper one.
+	sysDevBlockDir = filepath.Join(tmpdir, "sys/dev/block")
+	err = os.MkdirAll(sysDevBlockDir, testDirMode)
+	assert.NoError(err)
+
+	isDM, err := rootFsOnDeviceMapper(rootFs)
+	assert.NoError(err)
+	assert.False(isDM)
+
+	// Fake the "dm" sysfs entry for the device holding the rootfs.
+	stat := syscall.Stat_t{}
+	err = syscall.Stat(rootFs, &stat)
+	assert.NoError(err)
+
+	major := int((stat.Dev >> 8) & 0xfff)
+	minor := int((stat.Dev & 0xff) | ((stat.Dev >> 12) & 0xfff00))
+
+	dmPath := filepath.Join(sysDevBlockDir, fmt.Sprintf("%d:%d", major, minor), "dm")
+	err = os.MkdirAll(dmPath, testDirMode)
+	assert.NoError(err)
+
+	isDM, err = rootFsOnDeviceMapper(rootFs)
+	assert.NoError(err)
+	assert.True(isDM)
+}
@@ -18,9 +18,11 @@ package main
 import (
 	"fmt"
 	"os"
+	"sync"
 
 	vc "github.com/kata-containers/runtime/virtcontainers"
 	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/sirupsen/logrus"
 	"github.com/urfave/cli"
 )
 
@@ -42,14 +44,27 @@ EXAMPLE:
 			Name:  "force, f",
 			Usage: "Forcibly deletes the container if it is still running (uses SIGKILL)",
 		},
+		cli.BoolFlag{
+			Name:  "all-exited",
+			Usage: "Delete all containers that have exited (cannot be combined with container IDs)",
+		},
 	},
 	Action: func(context *cli.Context) error {
 		args := context.Args()
+		force := context.Bool("force")
+
+		if context.Bool("all-exited") {
+			if args.Present() {
+				return fmt.Errorf("--all-exited cannot be combined with container IDs")
+			}
+
+			return deleteAllExited(force)
+		}
+
 		if args.Present() == false {
 			return fmt.Errorf("Missing container ID, should at least provide one")
 		}
 
-		force := context.Bool("force")
 		for _, cID := range []string(args) {
 			if err := delete(cID, force); err != nil {
 				return err
@@ -60,6 +75,123 @@ EXAMPLE:
 	},
 }
 
+// maxParallelDeletes caps the number of containers deleted
+// concurrently during a bulk delete.
+const maxParallelDeletes = 8
+
+// allExitedContainers lists the exited containers, split into workload
+// containers and pod sandboxes. A sandbox is only reported when every
+// container of its pod has exited, so deleting it cannot take running
+// workloads down with it.
+func allExitedContainers() (workloads, sandboxes []string, err error) {
+	podStatusList, err := vci.ListPod()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, podStatus := range podStatusList {
+		podExited := true
+
+		for _, containerStatus := range podStatus.ContainersStatus {
+			if containerStatus.State.State != vc.StateStopped {
+				podExited = false
+			}
+		}
+
+		for _, containerStatus := range podStatus.ContainersStatus {
+			if containerStatus.State.State != vc.StateStopped {
+				continue
+			}
+
+			containerType, err := oci.GetContainerType(containerStatus.Annotations)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if containerType == vc.PodSandbox {
+				if podExited {
+					sandboxes = append(sandboxes, containerStatus.ID)
+				}
+			} else {
+				workloads = append(workloads, containerStatus.ID)
+			}
+		}
+	}
+
+	return workloads, sandboxes, nil
+}
+
+// deleteInParallel deletes the specified containers concurrently. The
+// pod state locks in the virtcontainers stack are per pod, so deletes
+// across pods are independent and only used to be serialised by the
+// runtime looping over them. The first error is returned, the rest are
+// logged.
+func deleteInParallel(containerIDs []string, force bool) error {
+	workers := maxParallelDeletes
+	if len(containerIDs) < workers {
+		workers = len(containerIDs)
+	}
+
+	if workers == 0 {
+		return nil
+	}
+
+	jobs := make(chan string)
+	errs := make(chan error, len(containerIDs))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for cID := range jobs {
+				if err := delete(cID, force); err != nil {
+					errs <- fmt.Errorf("%v: %v", cID, err)
+				}
+			}
+		}()
+	}
+
+	for _, cID := range containerIDs {
+		jobs <- cID
+	}
+
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	var firstErr error
+
+	for err := range errs {
+		ccLog.WithError(err).Error("bulk delete failure")
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// deleteAllExited deletes every exited container: workload containers
+// first (in parallel), then the sandboxes of fully exited pods, so a
+// sandbox never disappears under a workload delete for the same pod.
+func deleteAllExited(force bool) error {
+	workloads, sandboxes, err := allExitedContainers()
+	if err != nil {
+		return err
+	}
+
+	if err := deleteInParallel(workloads, force); err != nil {
+		return err
+	}
+
+	return deleteInParallel(sandboxes, force)
+}
+
 func delete(containerID string, force bool) error {
 	// Checks the MUST and MUST NOT from OCI runtime specification
 	status, podID, err := getExistingContainerInfo(containerID)
@@ -89,11 +221,50 @@ func delete(containerID string, force bool) error {
 		forceStop = true
 	}
 
+	// Core dumps have to be grabbed before the rootfs goes away with
+	// the container.
+	if enableCoreDump && ociSpec.Root != nil {
+		if count, err := collectCoreDumps(containerID, ociSpec.Root.Path); err != nil {
+			ccLog.WithError(err).WithField("container", containerID).Warn("failed to collect core dumps")
+		} else if count != 0 {
+			ccLog.WithFields(logrus.Fields{
+				"container": containerID,
+				"cores":     count,
+				"core-dir":  coreDumpDir,
+			}).Info("collected workload core dumps")
+		}
+	}
+
 	switch containerType {
 	case vc.PodSandbox:
+		// A pod that has to be force-deleted may have died
+		// unexpectedly (for instance after a guest kernel panic), so
+		// grab a post-mortem bundle before its state is destroyed.
+		if forceStop && enableCrashDump {
+			if bundleDir, err := collectCrashDump(podID); err != nil {
+				ccLog.WithError(err).WithField("pod", podID).Warn("failed to collect crash dump")
+			} else {
+				ccLog.WithFields(logrus.Fields{
+					"pod":        podID,
+					"crash-dump": bundleDir,
+				}).Info("collected crash dump bundle")
+			}
+		}
+
 		if err := deletePod(podID); err != nil {
 			return err
 		}
+
+		// Give any dedicated CPUs back to the pool.
+		if err := releaseCPUSet(podID); err != nil {
+			ccLog.WithError(err).WithField("pod", podID).Warn("failed to release pod cpuset")
+		}
+
+		if enableSandboxCgroup {
+			if err := removePodCgroup(podID); err != nil {
+				ccLog.WithError(err).WithField("pod", podID).Warn("failed to remove pod cgroup")
+			}
+		}
 	case vc.PodContainer:
 		if err := deleteContainer(podID, containerID, forceStop); err != nil {
 			return err
@@ -102,6 +273,12 @@ func delete(containerID string, force bool) error {
 		return fmt.Errorf("Invalid container type found")
 	}
 
+	// Drop the private host copies of any secrets injected into the
+	// container.
+	if err := removeInjectedSecrets(containerID); err != nil {
+		ccLog.WithError(err).WithField("container", containerID).Warn("failed to remove injected secrets")
+	}
+
 	// In order to prevent any file descriptor leak related to cgroups files
 	// that have been previously created, we have to remove them before this
 	// function returns.
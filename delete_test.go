@@ -595,3 +595,80 @@ func TestRemoveCGroupsPath(t *testing.T) {
 	err = removeCgroupsPath("foo", []string{dir})
 	assert.Error(err)
 }
+
+func TestAllExitedContainers(t *testing.T) {
+	assert := assert.New(t)
+
+	stopped := vc.State{State: vc.StateStopped}
+	running := vc.State{State: vc.StateRunning}
+
+	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
+		return []vc.PodStatus{
+			{
+				// fully exited pod: sandbox and workload both eligible
+				ID:    "pod1",
+				State: stopped,
+				ContainersStatus: []vc.ContainerStatus{
+					{
+						ID:    "pod1",
+						State: stopped,
+						Annotations: map[string]string{
+							vcAnnotations.ContainerTypeKey: string(vc.PodSandbox),
+						},
+					},
+					{
+						ID:    "workload1",
+						State: stopped,
+						Annotations: map[string]string{
+							vcAnnotations.ContainerTypeKey: string(vc.PodContainer),
+						},
+					},
+				},
+			},
+			{
+				// pod with a running workload: only the exited workload
+				// is eligible, never the sandbox
+				ID:    "pod2",
+				State: running,
+				ContainersStatus: []vc.ContainerStatus{
+					{
+						ID:    "pod2",
+						State: stopped,
+						Annotations: map[string]string{
+							vcAnnotations.ContainerTypeKey: string(vc.PodSandbox),
+						},
+					},
+					{
+						ID:    "workload2",
+						State: stopped,
+						Annotations: map[string]string{
+							vcAnnotations.ContainerTypeKey: string(vc.PodContainer),
+						},
+					},
+					{
+						ID:    "workload3",
+						State: running,
+						Annotations: map[string]string{
+							vcAnnotations.ContainerTypeKey: string(vc.PodContainer),
+						},
+					},
+				},
+			},
+		}, nil
+	}
+
+	defer func() {
+		testingImpl.ListPodFunc = nil
+	}()
+
+	workloads, sandboxes, err := allExitedContainers()
+	assert.NoError(err)
+	assert.Equal([]string{"workload1", "workload2"}, workloads)
+	assert.Equal([]string{"pod1"}, sandboxes)
+}
+
+func TestDeleteInParallelNoContainers(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(deleteInParallel(nil, false))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// devicesAnnotation is the annotation used by orchestrators (notably
+// Kubernetes device plugins via the CRI shim) to request host devices
+// that do not appear in the OCI linux.devices list. The value is a
+// comma-separated list of host device paths; VFIO group paths
+// ("/dev/vfio/<group>") result in full device passthrough.
+const devicesAnnotation = ccAnnotationsPrefix + "devices"
+
+// deviceFromPath creates an OCI device description for the specified
+// host device path.
+func deviceFromPath(path string) (specs.LinuxDevice, error) {
+	stat := syscall.Stat_t{}
+	if err := syscall.Stat(path, &stat); err != nil {
+		return specs.LinuxDevice{}, err
+	}
+
+	var devType string
+
+	switch stat.Mode & syscall.S_IFMT {
+	case syscall.S_IFCHR:
+		devType = "c"
+	case syscall.S_IFBLK:
+		devType = "b"
+	default:
+		return specs.LinuxDevice{}, fmt.Errorf("%s is not a device node", path)
+	}
+
+	return specs.LinuxDevice{
+		Path:  path,
+		Type:  devType,
+		Major: int64((stat.Rdev >> 8) & 0xfff),
+		Minor: int64((stat.Rdev & 0xff) | ((stat.Rdev >> 12) & 0xfff00)),
+	}, nil
+}
+
+// addDevicesFromAnnotations augments the OCI spec with the devices
+// requested through the devices annotation so that virtcontainers
+// attaches them to the VM (VFIO passthrough for IOMMU groups, generic
+// handling otherwise). Without this, device plugin allocations are
+// invisible inside the pod.
+func addDevicesFromAnnotations(ociSpec *oci.CompatOCISpec) error {
+	value, ok := ociSpec.Annotations[devicesAnnotation]
+	if !ok || value == "" {
+		return nil
+	}
+
+	if ociSpec.Linux == nil {
+		ociSpec.Linux = &specs.Linux{}
+	}
+
+	existing := make(map[string]bool)
+	for _, d := range ociSpec.Linux.Devices {
+		existing[d.Path] = true
+	}
+
+	for _, path := range strings.Split(value, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+
+		if existing[path] {
+			continue
+		}
+
+		device, err := deviceFromPath(path)
+		if err != nil {
+			return fmt.Errorf("invalid annotated device %q: %v", path, err)
+		}
+
+		ociSpec.Linux.Devices = append(ociSpec.Linux.Devices, device)
+		existing[path] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceFromPath(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := deviceFromPath("/this/path/does/not/exist")
+	assert.Error(err)
+
+	// a regular file is not a device node
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	file := filepath.Join(tmpdir, "not-a-device")
+	err = createEmptyFile(file)
+	assert.NoError(err)
+
+	_, err = deviceFromPath(file)
+	assert.Error(err)
+
+	// /dev/null is always available
+	device, err := deviceFromPath("/dev/null")
+	assert.NoError(err)
+
+	assert.Equal("/dev/null", device.Path)
+	assert.Equal("c", device.Type)
+	assert.Equal(int64(1), device.Major)
+	assert.Equal(int64(3), device.Minor)
+}
+
+func TestAddDevicesFromAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotations
+	err := addDevicesFromAnnotations(&ociSpec)
+	assert.NoError(err)
+	assert.Nil(ociSpec.Linux)
+
+	ociSpec.Annotations = map[string]string{
+		devicesAnnotation: "/dev/null, /dev/zero",
+	}
+
+	err = addDevicesFromAnnotations(&ociSpec)
+	assert.NoError(err)
+	assert.NotNil(ociSpec.Linux)
+	assert.Len(ociSpec.Linux.Devices, 2)
+	assert.Equal("/dev/null", ociSpec.Linux.Devices[0].Path)
+	assert.Equal("/dev/zero", ociSpec.Linux.Devices[1].Path)
+
+	// devices already in the spec must not be duplicated
+	err = addDevicesFromAnnotations(&ociSpec)
+	assert.NoError(err)
+	assert.Len(ociSpec.Linux.Devices, 2)
+
+	// an invalid device must fail the whole call
+	ociSpec.Annotations[devicesAnnotation] = "/this/path/does/not/exist"
+
+	err = addDevicesFromAnnotations(&ociSpec)
+	assert.Error(err)
+
+	// existing devices survive an earlier Linux section
+	ociSpec = oci.CompatOCISpec{}
+	ociSpec.Linux = &specs.Linux{
+		Devices: []specs.LinuxDevice{
+			{Path: "/dev/null", Type: "c", Major: 1, Minor: 3},
+		},
+	}
+	ociSpec.Annotations = map[string]string{
+		devicesAnnotation: "/dev/null",
+	}
+
+	err = addDevicesFromAnnotations(&ociSpec)
+	assert.NoError(err)
+	assert.Len(ociSpec.Linux.Devices, 1)
+}
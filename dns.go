@@ -0,0 +1,92 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+)
+
+// The DNS files are bind mounted file by file into the 9p shared
+// directory, which pins their inode. Orchestrators update them by
+// renaming a new file into place on the host (kubelet rewrites
+// resolv.conf this way), so the pinned inode keeps serving stale data
+// to the guest. Writing the current host content through the pinned
+// inode instead propagates the update to the guest, matching runc
+// behaviour where the container simply sees the host file.
+
+// dnsFileDestinations lists the in-container files whose host-side
+// changes must propagate into the guest.
+var dnsFileDestinations = map[string]bool{
+	"/etc/resolv.conf": true,
+	"/etc/hosts":       true,
+	"/etc/hostname":    true,
+}
+
+// syncDNSFiles refreshes the pinned shared-directory copies of the DNS
+// files of the specified container from their current host sources. It
+// returns the number of files that were updated. A container without
+// recorded mounts simply has nothing to sync.
+func syncDNSFiles(podID, containerID string) (int, error) {
+	mountsJSON := filepath.Join(podRunStoragePath, podID, containerID, "mounts.json")
+
+	contents, err := getFileContents(mountsJSON)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	var mounts []vc.Mount
+
+	if err := json.Unmarshal([]byte(contents), &mounts); err != nil {
+		return 0, err
+	}
+
+	synced := 0
+
+	for _, mount := range mounts {
+		if !dnsFileDestinations[mount.Destination] || mount.HostPath == "" {
+			continue
+		}
+
+		current, err := getFileContents(mount.Source)
+		if err != nil {
+			return synced, err
+		}
+
+		pinned, err := getFileContents(mount.HostPath)
+		if err != nil {
+			return synced, err
+		}
+
+		if current == pinned {
+			continue
+		}
+
+		// write (not rename) to keep the inode the guest sees
+		if err := writeFile(mount.HostPath, current, 0644); err != nil {
+			return synced, err
+		}
+
+		synced++
+	}
+
+	return synced, nil
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncDNSFiles(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+	const containerID = "container"
+
+	// no recorded mounts
+	synced, err := syncDNSFiles(podID, containerID)
+	assert.NoError(err)
+	assert.Equal(0, synced)
+
+	source := filepath.Join(tmpdir, "resolv.conf")
+	pinned := filepath.Join(tmpdir, "shared-resolv.conf")
+	otherSource := filepath.Join(tmpdir, "data")
+	otherPinned := filepath.Join(tmpdir, "shared-data")
+
+	for file, contents := range map[string]string{
+		source:      "nameserver 10.0.0.2\n",
+		pinned:      "nameserver 10.0.0.1\n",
+		otherSource: "new\n",
+		otherPinned: "old\n",
+	} {
+		err = ioutil.WriteFile(file, []byte(contents), 0644)
+		assert.NoError(err)
+	}
+
+	mounts := []vc.Mount{
+		{
+			Source:      source,
+			Destination: "/etc/resolv.conf",
+			Type:        "bind",
+			HostPath:    pinned,
+		},
+		{
+			Source:      otherSource,
+			Destination: "/data",
+			Type:        "bind",
+			HostPath:    otherPinned,
+		},
+	}
+
+	bytes, err := json.Marshal(mounts)
+	assert.NoError(err)
+
+	containerDir := filepath.Join(tmpdir, podID, containerID)
+	err = os.MkdirAll(containerDir, 0750)
+	assert.NoError(err)
+
+	err = ioutil.WriteFile(filepath.Join(containerDir, "mounts.json"), bytes, 0644)
+	assert.NoError(err)
+
+	synced, err = syncDNSFiles(podID, containerID)
+	assert.NoError(err)
+	assert.Equal(1, synced)
+
+	// the DNS file was refreshed
+	contents, err := getFileContents(pinned)
+	assert.NoError(err)
+	assert.Equal("nameserver 10.0.0.2\n", contents)
+
+	// the non-DNS mount was left alone
+	contents, err = getFileContents(otherPinned)
+	assert.NoError(err)
+	assert.Equal("old\n", contents)
+
+	// nothing to do the second time around
+	synced, err = syncDNSFiles(podID, containerID)
+	assert.NoError(err)
+	assert.Equal(0, synced)
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// Environment variables and secrets referenced by annotation are
+// resolved at create time and merged into the in-memory spec only:
+// the values reach the workload through the agent channel and a
+// private tmpfs copy, never through the config.json left in the
+// bundle.
+
+// envFileAnnotation names a host file holding KEY=VALUE lines to be
+// appended to the container environment.
+const envFileAnnotation = ccAnnotationsPrefix + "env_file"
+
+// secretsAnnotation lists secret files to expose to the container, as
+// a comma-separated list of name=hostpath entries. Each secret appears
+// in the container below secretsGuestDir under its name.
+const secretsAnnotation = ccAnnotationsPrefix + "secrets"
+
+// secretsGuestDir is where injected secrets appear in the container.
+const secretsGuestDir = "/run/secrets"
+
+// secretsRunDir is the host directory holding the per-container
+// private copies of injected secrets ("var" to allow tests to modify
+// it).
+var secretsRunDir = "/run/" + name + "/secrets"
+
+// maxInjectedFileSize caps env files and secrets: the mechanism is for
+// credentials and settings, not data distribution.
+const maxInjectedFileSize = 64 * 1024
+
+// secretNamePattern restricts secret names to filenames that cannot
+// escape the secrets directory.
+var secretNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// readInjectedFile returns the contents of a file to be injected,
+// enforcing the size cap.
+func readInjectedFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() > maxInjectedFileSize {
+		return "", fmt.Errorf("File %v too large to inject (%d bytes, limit %d)", path, info.Size(), maxInjectedFileSize)
+	}
+
+	return getFileContents(path)
+}
+
+// parseEnvFile parses KEY=VALUE lines. Blank lines and comments are
+// skipped, anything else malformed is an error rather than silently
+// dropped.
+func parseEnvFile(contents string) ([]string, error) {
+	var envs []string
+
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("Invalid environment file line %q", line)
+		}
+
+		envs = append(envs, line)
+	}
+
+	return envs, nil
+}
+
+// injectEnvFromAnnotations appends the variables from the annotated
+// environment file to the container environment. The merge happens in
+// memory only; the bundle config.json is left untouched.
+func injectEnvFromAnnotations(ociSpec *oci.CompatOCISpec) error {
+	path, ok := ociSpec.Annotations[envFileAnnotation]
+	if !ok || path == "" {
+		return nil
+	}
+
+	contents, err := readInjectedFile(path)
+	if err != nil {
+		return err
+	}
+
+	envs, err := parseEnvFile(contents)
+	if err != nil {
+		return err
+	}
+
+	if ociSpec.Process == nil {
+		return fmt.Errorf("Cannot inject environment: spec has no process")
+	}
+
+	// appended last so the file wins over config.json duplicates
+	ociSpec.Process.Env = append(ociSpec.Process.Env, envs...)
+
+	return nil
+}
+
+// injectSecretsFromAnnotations copies the annotated secret files into
+// a private per-container host directory and adds bind mounts exposing
+// them below secretsGuestDir in the container.
+func injectSecretsFromAnnotations(ociSpec *oci.CompatOCISpec, containerID string) error {
+	value, ok := ociSpec.Annotations[secretsAnnotation]
+	if !ok || value == "" {
+		return nil
+	}
+
+	containerDir := filepath.Join(secretsRunDir, containerID)
+
+	if err := os.MkdirAll(containerDir, 0700); err != nil {
+		return err
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || !secretNamePattern.MatchString(parts[0]) || parts[1] == "" {
+			return fmt.Errorf("Invalid secret entry %q (expected name=hostpath)", entry)
+		}
+
+		contents, err := readInjectedFile(parts[1])
+		if err != nil {
+			return err
+		}
+
+		hostCopy := filepath.Join(containerDir, parts[0])
+
+		if err := writeFile(hostCopy, contents, 0600); err != nil {
+			return err
+		}
+
+		ociSpec.Mounts = append(ociSpec.Mounts, specs.Mount{
+			Source:      hostCopy,
+			Destination: filepath.Join(secretsGuestDir, parts[0]),
+			Type:        "bind",
+			Options:     []string{"bind", "ro"},
+		})
+	}
+
+	return nil
+}
+
+// removeInjectedSecrets drops the private host copies of the secrets
+// injected into the specified container.
+func removeInjectedSecrets(containerID string) error {
+	return os.RemoveAll(filepath.Join(secretsRunDir, containerID))
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	assert := assert.New(t)
+
+	// comments and blank lines are skipped, values may contain '='
+	envs, err := parseEnvFile("# comment\n\nFOO=bar\nTOKEN=a=b\n")
+	assert.NoError(err)
+	assert.Equal([]string{"FOO=bar", "TOKEN=a=b"}, envs)
+
+	// malformed lines are errors, not silently dropped
+	for _, contents := range []string{"FOO", "=bar"} {
+		_, err = parseEnvFile(contents)
+		assert.Error(err)
+	}
+}
+
+func TestInjectEnvFromAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotation
+	assert.NoError(injectEnvFromAnnotations(&ociSpec))
+
+	envFile := filepath.Join(tmpdir, "env")
+	err = ioutil.WriteFile(envFile, []byte("FOO=bar\n"), 0600)
+	assert.NoError(err)
+
+	ociSpec.Annotations = map[string]string{
+		envFileAnnotation: envFile,
+	}
+
+	// annotated but no process to extend
+	assert.Error(injectEnvFromAnnotations(&ociSpec))
+
+	ociSpec.Process = &oci.CompatOCIProcess{}
+	ociSpec.Process.Env = []string{"PATH=/bin"}
+
+	assert.NoError(injectEnvFromAnnotations(&ociSpec))
+	assert.Equal([]string{"PATH=/bin", "FOO=bar"}, ociSpec.Process.Env)
+
+	// missing file
+	ociSpec.Annotations[envFileAnnotation] = filepath.Join(tmpdir, "missing")
+	assert.Error(injectEnvFromAnnotations(&ociSpec))
+
+	// oversized file
+	big := filepath.Join(tmpdir, "big")
+	err = ioutil.WriteFile(big, []byte(strings.Repeat("A=B\n", maxInjectedFileSize)), 0600)
+	assert.NoError(err)
+
+	ociSpec.Annotations[envFileAnnotation] = big
+	assert.Error(injectEnvFromAnnotations(&ociSpec))
+}
+
+func TestInjectSecretsFromAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedSecretsRunDir := secretsRunDir
+
+	defer func() {
+		secretsRunDir = savedSecretsRunDir
+	}()
+
+	secretsRunDir = filepath.Join(tmpdir, "secrets")
+
+	const containerID = "container"
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotation
+	assert.NoError(injectSecretsFromAnnotations(&ociSpec, containerID))
+	assert.Empty(ociSpec.Mounts)
+
+	secretFile := filepath.Join(tmpdir, "password")
+	err = ioutil.WriteFile(secretFile, []byte("hunter2"), 0600)
+	assert.NoError(err)
+
+	ociSpec.Annotations = map[string]string{
+		secretsAnnotation: "db-password=" + secretFile,
+	}
+
+	assert.NoError(injectSecretsFromAnnotations(&ociSpec, containerID))
+
+	hostCopy := filepath.Join(secretsRunDir, containerID, "db-password")
+
+	contents, err := ioutil.ReadFile(hostCopy)
+	assert.NoError(err)
+	assert.Equal("hunter2", string(contents))
+
+	assert.Len(ociSpec.Mounts, 1)
+	assert.Equal(hostCopy, ociSpec.Mounts[0].Source)
+	assert.Equal("/run/secrets/db-password", ociSpec.Mounts[0].Destination)
+	assert.Equal("bind", ociSpec.Mounts[0].Type)
+
+	// names that could escape the secrets directory are rejected
+	for _, entry := range []string{"../evil=" + secretFile, "name", "name=", "=path"} {
+		ociSpec.Annotations[secretsAnnotation] = entry
+		assert.Error(injectSecretsFromAnnotations(&ociSpec, containerID))
+	}
+
+	// missing secret file
+	ociSpec.Annotations[secretsAnnotation] = "missing=" + filepath.Join(tmpdir, "missing")
+	assert.Error(injectSecretsFromAnnotations(&ociSpec, containerID))
+
+	// delete drops the private copies
+	assert.NoError(removeInjectedSecrets(containerID))
+
+	_, err = os.Stat(hostCopy)
+	assert.True(os.IsNotExist(err))
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "encoding/json"
+
+// Exit codes, one per error class, so that callers can tell the broad
+// failure categories apart without parsing error strings. Agent
+// timeouts are the only retriable class: the pod may simply be under
+// load.
+const (
+	exitGeneric      = 1
+	exitConfigError  = 2
+	exitSpecError    = 3
+	exitHypervisor   = 4
+	exitAgentTimeout = 5
+)
+
+// Error class names as reported in the JSON error block.
+const (
+	classGeneric      = "generic"
+	classConfig       = "config"
+	classSpec         = "spec"
+	classHypervisor   = "hypervisor"
+	classAgentTimeout = "agent-timeout"
+)
+
+// runtimeError associates an error with a class and the exit code the
+// runtime terminates with when the error reaches fatal().
+type runtimeError struct {
+	class    string
+	exitCode int
+	err      error
+}
+
+func (e runtimeError) Error() string {
+	return e.err.Error()
+}
+
+// configError classifies err as a configuration problem (bad or
+// missing configuration file values).
+func configError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return runtimeError{class: classConfig, exitCode: exitConfigError, err: err}
+}
+
+// specError classifies err as an OCI specification problem (invalid or
+// unparsable config.json).
+func specError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return runtimeError{class: classSpec, exitCode: exitSpecError, err: err}
+}
+
+// hypervisorError classifies err as a hypervisor problem (the VM could
+// not be created or started).
+func hypervisorError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return runtimeError{class: classHypervisor, exitCode: exitHypervisor, err: err}
+}
+
+// agentTimeoutError classifies err as a timed-out guest agent request.
+// This is the only retriable class.
+func agentTimeoutError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return runtimeError{class: classAgentTimeout, exitCode: exitAgentTimeout, err: err}
+}
+
+// errorExitCode returns the exit code associated with the specified
+// error.
+func errorExitCode(err error) int {
+	if rtErr, ok := err.(runtimeError); ok {
+		return rtErr.exitCode
+	}
+
+	return exitGeneric
+}
+
+// errorClass returns the class name associated with the specified
+// error.
+func errorClass(err error) string {
+	if rtErr, ok := err.(runtimeError); ok {
+		return rtErr.class
+	}
+
+	return classGeneric
+}
+
+// errorJSON renders the specified error as the machine-parsable block
+// written to stderr when JSON logging is selected.
+func errorJSON(err error) string {
+	block := struct {
+		Error    string `json:"error"`
+		Class    string `json:"class"`
+		ExitCode int    `json:"exit-code"`
+	}{
+		Error:    err.Error(),
+		Class:    errorClass(err),
+		ExitCode: errorExitCode(err),
+	}
+
+	bytes, marshalErr := json.Marshal(block)
+	if marshalErr != nil {
+		// fall back to the plain error text
+		return err.Error()
+	}
+
+	return string(bytes)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorClassification(t *testing.T) {
+	assert := assert.New(t)
+
+	err := errors.New("foo")
+
+	tests := []struct {
+		wrap     func(error) error
+		class    string
+		exitCode int
+	}{
+		{configError, classConfig, exitConfigError},
+		{specError, classSpec, exitSpecError},
+		{hypervisorError, classHypervisor, exitHypervisor},
+		{agentTimeoutError, classAgentTimeout, exitAgentTimeout},
+	}
+
+	for _, test := range tests {
+		wrapped := test.wrap(err)
+		assert.Equal(err.Error(), wrapped.Error())
+		assert.Equal(test.class, errorClass(wrapped))
+		assert.Equal(test.exitCode, errorExitCode(wrapped))
+
+		// nil errors stay nil
+		assert.NoError(test.wrap(nil))
+	}
+
+	// unclassified errors
+	assert.Equal(classGeneric, errorClass(err))
+	assert.Equal(exitGeneric, errorExitCode(err))
+}
+
+func TestErrorJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	block := struct {
+		Error    string `json:"error"`
+		Class    string `json:"class"`
+		ExitCode int    `json:"exit-code"`
+	}{}
+
+	err := json.Unmarshal([]byte(errorJSON(configError(errors.New("foo")))), &block)
+	assert.NoError(err)
+	assert.Equal("foo", block.Error)
+	assert.Equal(classConfig, block.Class)
+	assert.Equal(exitConfigError, block.ExitCode)
+
+	err = json.Unmarshal([]byte(errorJSON(errors.New("bar"))), &block)
+	assert.NoError(err)
+	assert.Equal(classGeneric, block.Class)
+	assert.Equal(exitGeneric, block.ExitCode)
+}
@@ -215,6 +215,15 @@ func execute(context *cli.Context) error {
 		return fmt.Errorf("Container %s is not running", params.cID)
 	}
 
+	// Opportunistically refresh the DNS files the guest sees: exec is
+	// the first runtime invocation after kubelet rewrites resolv.conf
+	// (liveness probes run through here). Best effort only.
+	if synced, err := syncDNSFiles(podID, params.cID); err != nil {
+		ccLog.WithError(err).WithField("container", params.cID).Warn("cannot sync DNS files")
+	} else if synced != 0 {
+		ccLog.WithField("synced-files", synced).Info("refreshed guest DNS files from host")
+	}
+
 	envVars, err := oci.EnvVars(params.ociProcess.Env)
 	if err != nil {
 		return err
@@ -241,7 +250,19 @@ func execute(context *cli.Context) error {
 		Detach:      noNeedForOutput(params.detach, params.ociProcess.Terminal),
 	}
 
-	_, _, process, err := vci.EnterContainer(podID, params.cID, cmd)
+	// Make sure the agent channel is alive before committing to the
+	// enter: a wedged agent should fail fast, not hang the exec.
+	if err := pingAgent(podID); err != nil {
+		return err
+	}
+
+	var process *vc.Process
+
+	err = withAgentTimeout("exec", func() error {
+		var enterErr error
+		_, _, process, enterErr = vci.EnterContainer(podID, params.cID, cmd)
+		return enterErr
+	})
 	if err != nil {
 		return err
 	}
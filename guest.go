@@ -0,0 +1,192 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// ccAnnotationsPrefix is the prefix used for annotations specific to
+// this runtime.
+const ccAnnotationsPrefix = "com.github.clearcontainers.runtime."
+
+// guestModulesAnnotation is the annotation used to request a
+// comma-separated list of kernel modules to be loaded inside the guest
+// when the pod boots.
+const guestModulesAnnotation = ccAnnotationsPrefix + "guest_modules"
+
+// guestModulesWhitelist lists the kernel modules a pod is allowed to
+// request via guestModulesAnnotation ("var" to allow tests to modify
+// it).
+var guestModulesWhitelist = map[string]bool{
+	"br_netfilter":      true,
+	"ip_vs":             true,
+	"nf_conntrack":      true,
+	"nf_conntrack_ipv4": true,
+	"xt_conntrack":      true,
+	ptpKvmModule:        true,
+}
+
+// guestOCISysctls translates the OCI linux.sysctl map into sysctls to
+// apply inside the guest through the agent.
+func guestOCISysctls(ociSpec oci.CompatOCISpec) []guestSysctl {
+	if ociSpec.Linux == nil || len(ociSpec.Linux.Sysctl) == 0 {
+		return nil
+	}
+
+	// sort the keys for deterministic application
+	keys := []string{}
+	for key := range ociSpec.Linux.Sysctl {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var sysctls []guestSysctl
+
+	for _, key := range keys {
+		sysctls = append(sysctls, guestSysctl{
+			key:   key,
+			value: ociSpec.Linux.Sysctl[key],
+		})
+	}
+
+	return sysctls
+}
+
+// podGuestSysctls gathers the sysctls requested for the guest of a
+// pod. They are applied through the agent once the pod runs, since
+// they cannot be applied from the host.
+func podGuestSysctls(ociSpec oci.CompatOCISpec) ([]guestSysctl, error) {
+	sysctls := guestOCISysctls(ociSpec)
+	sysctls = append(sysctls, guestPidsLimitSysctls(ociSpec)...)
+
+	ioSysctls, err := ioPresetSysctls(ociSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	sysctls = append(sysctls, ioSysctls...)
+
+	if enableCoreDump {
+		sysctls = append(sysctls, coreDumpGuestSysctls()...)
+	}
+
+	return sysctls, nil
+}
+
+// guestPidsReserve is the number of process IDs reserved on top of the
+// workload pids limit for the agent, systemd and kernel threads.
+const guestPidsReserve = 64
+
+// minGuestPidMax is the smallest value the kernel accepts for
+// kernel.pid_max.
+const minGuestPidMax = 301
+
+// guestPidsLimitSysctls translates the OCI linux.resources.pids limit
+// into a cap on the guest kernel.pid_max. The limit itself is enforced
+// by the pids cgroup inside the guest; capping pid_max as well ensures
+// a fork bomb in the workload cannot exhaust the process table of the
+// VM and wedge the agent.
+func guestPidsLimitSysctls(ociSpec oci.CompatOCISpec) []guestSysctl {
+	if ociSpec.Linux == nil ||
+		ociSpec.Linux.Resources == nil ||
+		ociSpec.Linux.Resources.Pids == nil ||
+		ociSpec.Linux.Resources.Pids.Limit <= 0 {
+		return nil
+	}
+
+	pidMax := ociSpec.Linux.Resources.Pids.Limit + guestPidsReserve
+	if pidMax < minGuestPidMax {
+		pidMax = minGuestPidMax
+	}
+
+	return []guestSysctl{
+		{
+			key:   "kernel.pid_max",
+			value: fmt.Sprintf("%d", pidMax),
+		},
+	}
+}
+
+// guestModulesParams translates the guest modules annotation into a
+// kernel parameter understood by systemd-modules-load(8). Only
+// whitelisted modules may be requested.
+func guestModulesParams(ociSpec oci.CompatOCISpec) ([]vc.Param, error) {
+	value, ok := ociSpec.Annotations[guestModulesAnnotation]
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	var modules []string
+
+	for _, module := range strings.Split(value, ",") {
+		module = strings.TrimSpace(module)
+		if module == "" {
+			continue
+		}
+
+		if !guestModulesWhitelist[module] {
+			return nil, fmt.Errorf("Guest kernel module %q is not whitelisted", module)
+		}
+
+		modules = append(modules, module)
+	}
+
+	if len(modules) == 0 {
+		return nil, nil
+	}
+
+	return []vc.Param{
+		{
+			Key:   "modules-load",
+			Value: strings.Join(modules, ","),
+		},
+	}, nil
+}
+
+// addGuestConfigParams adds the per-pod guest configuration that rides
+// the guest kernel command line (annotation-requested kernel modules,
+// honoured by systemd-modules-load(8) at boot). Sysctls take the agent
+// channel instead, see podGuestSysctls.
+func addGuestConfigParams(ociSpec oci.CompatOCISpec, runtimeConfig *oci.RuntimeConfig) error {
+	var params []vc.Param
+
+	for _, rlimit := range unsupportedRlimits(ociSpec) {
+		// the agent protocol cannot carry per-process rlimits, so the
+		// divergence from runc behaviour is at least made visible
+		ccLog.WithField("rlimit", rlimit).Warn("rlimit cannot be applied inside the guest")
+	}
+
+	moduleParams, err := guestModulesParams(ociSpec)
+	if err != nil {
+		return err
+	}
+
+	params = append(params, moduleParams...)
+
+	for _, p := range params {
+		if err := runtimeConfig.AddKernelParam(p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuestOCISysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no Linux section
+	assert.Empty(guestOCISysctls(ociSpec))
+
+	ociSpec.Linux = &specs.Linux{}
+
+	// no sysctls
+	assert.Empty(guestOCISysctls(ociSpec))
+
+	ociSpec.Linux.Sysctl = map[string]string{
+		"net.ipv4.ip_forward":      "1",
+		"kernel.shm_rmid_forced":   "0",
+		"net.core.somaxconn":       "1024",
+		"fs.mqueue.queues_max":     "64",
+		"net.ipv4.tcp_fin_timeout": "30",
+	}
+
+	expected := []guestSysctl{
+		{key: "fs.mqueue.queues_max", value: "64"},
+		{key: "kernel.shm_rmid_forced", value: "0"},
+		{key: "net.core.somaxconn", value: "1024"},
+		{key: "net.ipv4.ip_forward", value: "1"},
+		{key: "net.ipv4.tcp_fin_timeout", value: "30"},
+	}
+
+	assert.Equal(expected, guestOCISysctls(ociSpec))
+}
+
+func TestGuestPidsLimitSysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no Linux section
+	assert.Empty(guestPidsLimitSysctls(ociSpec))
+
+	ociSpec.Linux = &specs.Linux{}
+
+	// no resources
+	assert.Empty(guestPidsLimitSysctls(ociSpec))
+
+	ociSpec.Linux.Resources = &specs.LinuxResources{
+		Pids: &specs.LinuxPids{},
+	}
+
+	// no limit set
+	assert.Empty(guestPidsLimitSysctls(ociSpec))
+
+	ociSpec.Linux.Resources.Pids.Limit = 1024
+
+	assert.Equal([]guestSysctl{
+		{key: "kernel.pid_max", value: "1088"},
+	}, guestPidsLimitSysctls(ociSpec))
+
+	// tiny limits are raised to the kernel minimum for pid_max
+	ociSpec.Linux.Resources.Pids.Limit = 16
+
+	assert.Equal([]guestSysctl{
+		{key: "kernel.pid_max", value: "301"},
+	}, guestPidsLimitSysctls(ociSpec))
+}
+
+func TestPodGuestSysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Linux = &specs.Linux{
+		Sysctl: map[string]string{
+			"net.ipv4.ip_forward": "1",
+		},
+		Resources: &specs.LinuxResources{
+			Pids: &specs.LinuxPids{Limit: 1024},
+		},
+	}
+
+	sysctls, err := podGuestSysctls(ociSpec)
+	assert.NoError(err)
+
+	assert.Equal([]guestSysctl{
+		{key: "net.ipv4.ip_forward", value: "1"},
+		{key: "kernel.pid_max", value: "1088"},
+	}, sysctls)
+}
+
+func TestGuestModulesParams(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotations
+	params, err := guestModulesParams(ociSpec)
+	assert.NoError(err)
+	assert.Empty(params)
+
+	ociSpec.Annotations = map[string]string{
+		guestModulesAnnotation: "",
+	}
+
+	// empty annotation value
+	params, err = guestModulesParams(ociSpec)
+	assert.NoError(err)
+	assert.Empty(params)
+
+	ociSpec.Annotations[guestModulesAnnotation] = "nf_conntrack, br_netfilter"
+
+	params, err = guestModulesParams(ociSpec)
+	assert.NoError(err)
+	assert.Equal([]vc.Param{
+		{Key: "modules-load", Value: "nf_conntrack,br_netfilter"},
+	}, params)
+
+	// non-whitelisted module
+	ociSpec.Annotations[guestModulesAnnotation] = "nf_conntrack,evil_module"
+
+	_, err = guestModulesParams(ociSpec)
+	assert.Error(err)
+}
+
+func TestAddGuestConfigParams(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+	ociSpec.Linux = &specs.Linux{
+		Sysctl: map[string]string{
+			"net.ipv4.ip_forward": "1",
+		},
+	}
+	ociSpec.Annotations = map[string]string{
+		guestModulesAnnotation: "nf_conntrack",
+	}
+
+	config := oci.RuntimeConfig{}
+
+	err := addGuestConfigParams(ociSpec, &config)
+	assert.NoError(err)
+
+	// OCI sysctls take the agent channel, not the command line
+	expected := []vc.Param{
+		{Key: "modules-load", Value: "nf_conntrack"},
+	}
+
+	assert.Equal(expected, config.HypervisorConfig.KernelParams)
+
+	// a non-whitelisted module must fail the whole call
+	ociSpec.Annotations[guestModulesAnnotation] = "evil_module"
+
+	err = addGuestConfigParams(ociSpec, &config)
+	assert.Error(err)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	proxyClient "github.com/clearcontainers/proxy/client"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/hyperstart"
+)
+
+// The only channel into a running guest is the agent: hyperstart
+// executes commands relayed through the per-pod proxy and runs them in
+// the guest root context when no container is specified. Guest sysctls
+// are delivered that way. They cannot ride the kernel command line:
+// systemd-sysctl only reads sysctl.d files, and the guest kernels
+// predate the kernel's own "sysctl." boot parameter support, so such
+// parameters are silently dropped.
+
+// guestSysctl is one sysctl to apply inside the guest.
+type guestSysctl struct {
+	key   string
+	value string
+}
+
+// guestExecTimeout bounds the whole proxy exchange of a guest command
+// so a wedged proxy or agent cannot hang the runtime.
+const guestExecTimeout = 10 * time.Second
+
+// sysctlKeyPattern restricts sysctl keys to names that map onto paths
+// below /proc/sys and cannot smuggle shell metacharacters into the
+// applying command.
+var sysctlKeyPattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// shellQuote returns the string as a single shell word, safe to embed
+// in a command line whatever it contains.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// guestExec runs the specified command inside the guest of the
+// specified pod, relayed to the agent through the pod proxy. No
+// container is given in the command, so the agent executes it in the
+// guest root context, against the guest image rootfs.
+func guestExec(podID string, args []string) error {
+	socket := filepath.Join(podRunStoragePath, podID, "proxy.sock")
+
+	conn, err := net.DialTimeout("unix", socket, agentProbeTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(guestExecTimeout)); err != nil {
+		return err
+	}
+
+	client := proxyClient.NewClient(conn)
+
+	attach, err := client.AttachVM(podID, &proxyClient.AttachVMOptions{NumIOStreams: 1})
+	if err != nil {
+		return err
+	}
+
+	if len(attach.IO.Tokens) != 1 {
+		return fmt.Errorf("BUG: expected 1 I/O token from the proxy, got %d", len(attach.IO.Tokens))
+	}
+
+	execCommand := hyperstart.ExecCommand{
+		Process: hyperstart.Process{
+			Args:    args,
+			Workdir: "/",
+		},
+	}
+
+	if _, err := client.HyperWithTokens(hyperstart.ExecCmd, attach.IO.Tokens, execCommand); err != nil {
+		return fmt.Errorf("guest command %v failed: %v", args, err)
+	}
+
+	return nil
+}
+
+// applyGuestSysctls applies the specified sysctls inside the guest of
+// the specified pod, writing them below /proc/sys with a single shell
+// invocation in the guest root context.
+func applyGuestSysctls(podID string, sysctls []guestSysctl) error {
+	if len(sysctls) == 0 {
+		return nil
+	}
+
+	var script []string
+
+	for _, sysctl := range sysctls {
+		if !sysctlKeyPattern.MatchString(sysctl.key) {
+			return fmt.Errorf("Invalid sysctl key %q", sysctl.key)
+		}
+
+		path := "/proc/sys/" + strings.Replace(sysctl.key, ".", "/", -1)
+		script = append(script, fmt.Sprintf("printf '%%s' %s > %s", shellQuote(sysctl.value), path))
+	}
+
+	return guestExec(podID, []string{"/bin/sh", "-ec", strings.Join(script, "\n")})
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	proxyAPI "github.com/clearcontainers/proxy/api"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/hyperstart"
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeProxy serves a single client connection on the proxy socket
+// of the specified pod, answering AttachVM and Hyper commands the way
+// the real proxy would. The Hyper payloads received are sent down the
+// returned channel.
+func startFakeProxy(t *testing.T, tmpdir, podID string) (net.Listener, chan proxyAPI.Hyper) {
+	assert := assert.New(t)
+
+	err := os.MkdirAll(filepath.Join(tmpdir, podID), 0750)
+	assert.NoError(err)
+
+	listener, err := net.Listen("unix", filepath.Join(tmpdir, podID, "proxy.sock"))
+	assert.NoError(err)
+
+	hyperCh := make(chan proxyAPI.Hyper, 1)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			frame, err := proxyAPI.ReadFrame(conn)
+			if err != nil {
+				return
+			}
+
+			var payload []byte
+
+			switch proxyAPI.Command(frame.Header.Opcode) {
+			case proxyAPI.CmdAttachVM:
+				payload = []byte(`{"io":{"url":"","tokens":["test-token"]}}`)
+			case proxyAPI.CmdHyper:
+				var hyper proxyAPI.Hyper
+				if err := json.Unmarshal(frame.Payload, &hyper); err != nil {
+					return
+				}
+				hyperCh <- hyper
+			}
+
+			response := proxyAPI.NewFrame(proxyAPI.TypeResponse, frame.Header.Opcode, payload)
+			if err := proxyAPI.WriteFrame(conn, response); err != nil {
+				return
+			}
+		}
+	}()
+
+	return listener, hyperCh
+}
+
+func TestGuestExec(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+
+	// no proxy socket
+	err = guestExec(podID, []string{"/bin/true"})
+	assert.Error(err)
+
+	listener, hyperCh := startFakeProxy(t, tmpdir, podID)
+	defer listener.Close()
+
+	err = guestExec(podID, []string{"/bin/true"})
+	assert.NoError(err)
+
+	hyper := <-hyperCh
+	assert.Equal(hyperstart.ExecCmd, hyper.HyperName)
+	assert.Equal([]string{"test-token"}, hyper.Tokens)
+
+	var execCommand hyperstart.ExecCommand
+	err = json.Unmarshal(hyper.Data, &execCommand)
+	assert.NoError(err)
+
+	// no container: the command runs in the guest root context
+	assert.Empty(execCommand.Container)
+	assert.Equal([]string{"/bin/true"}, execCommand.Process.Args)
+}
+
+func TestApplyGuestSysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+
+	// nothing to apply: the proxy must not even be contacted
+	assert.NoError(applyGuestSysctls(podID, nil))
+
+	// keys that do not map below /proc/sys are rejected
+	err = applyGuestSysctls(podID, []guestSysctl{{key: "foo;rm -rf /", value: "1"}})
+	assert.Error(err)
+
+	listener, hyperCh := startFakeProxy(t, tmpdir, podID)
+	defer listener.Close()
+
+	err = applyGuestSysctls(podID, []guestSysctl{
+		{key: "net.ipv4.ip_forward", value: "1"},
+		{key: "kernel.core_pattern", value: "core.%e.%p"},
+	})
+	assert.NoError(err)
+
+	hyper := <-hyperCh
+
+	var execCommand hyperstart.ExecCommand
+	err = json.Unmarshal(hyper.Data, &execCommand)
+	assert.NoError(err)
+
+	assert.Equal([]string{"/bin/sh", "-ec",
+		"printf '%s' '1' > /proc/sys/net/ipv4/ip_forward\n" +
+			"printf '%s' 'core.%e.%p' > /proc/sys/kernel/core_pattern"},
+		execCommand.Process.Args)
+}
+
+func TestShellQuote(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("'simple'", shellQuote("simple"))
+	assert.Equal(`'it'\''s'`, shellQuote("it's"))
+	assert.Equal("'a $b `c`'", shellQuote("a $b `c`"))
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path/filepath"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// annotation CRI-O uses to mark the infra ("pause") container of a
+// pod.
+const criContainerTypeAnnotation = "io.kubernetes.cri-o.ContainerType"
+
+// criContainerTypeSandbox is the criContainerTypeAnnotation value
+// identifying the infra container.
+const criContainerTypeSandbox = "sandbox"
+
+// isInfraContainer returns true if the spec describes the infra
+// (pause) container of a CRI-driven pod. Its only purpose is to hold
+// the pod resources, a job the VM itself already does for this
+// runtime. The pause workload cannot be elided entirely without agent
+// support, but callers use this to skip accounting and per-workload
+// processing that only makes sense for real workloads.
+func isInfraContainer(ociSpec oci.CompatOCISpec) bool {
+	if ociSpec.Annotations[criContainerTypeAnnotation] != criContainerTypeSandbox {
+		return false
+	}
+
+	if ociSpec.Process == nil || len(ociSpec.Process.Args) == 0 {
+		return false
+	}
+
+	return filepath.Base(ociSpec.Process.Args[0]) == "pause"
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInfraContainer(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotations, no process
+	assert.False(isInfraContainer(ociSpec))
+
+	ociSpec.Annotations = map[string]string{
+		criContainerTypeAnnotation: criContainerTypeSandbox,
+	}
+
+	// no process args
+	assert.False(isInfraContainer(ociSpec))
+
+	ociSpec.Process = &oci.CompatOCIProcess{}
+	ociSpec.Process.Args = []string{"/pause"}
+
+	assert.True(isInfraContainer(ociSpec))
+
+	// sandbox annotation but a real workload
+	ociSpec.Process.Args = []string{"/usr/bin/nginx"}
+	assert.False(isInfraContainer(ociSpec))
+
+	// pause binary but not marked as the sandbox
+	ociSpec.Process.Args = []string{"/pause"}
+	ociSpec.Annotations[criContainerTypeAnnotation] = "container"
+	assert.False(isInfraContainer(ociSpec))
+}
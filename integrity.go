@@ -0,0 +1,153 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// expected artifact digests from the [hypervisor.qemu] section of the
+// configuration file. Empty values disable verification.
+var (
+	kernelDigest = ""
+	imageDigest  = ""
+)
+
+// integrityCacheDir is where verified digests are cached so the
+// (potentially large) artifacts are not re-hashed on every create
+// ("var" to allow tests to modify it).
+var integrityCacheDir = "/run/" + name + "/integrity"
+
+// sha256File returns the hex-encoded SHA-256 digest of the specified
+// file.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// cachedDigest returns the previously verified digest of the
+// specified file, or an empty string if the cache entry is missing or
+// stale (the file changed since it was written).
+func cachedDigest(path string, info os.FileInfo) string {
+	contents, err := getFileContents(cachePath(path))
+	if err != nil {
+		return ""
+	}
+
+	fields := strings.Fields(contents)
+	if len(fields) != 3 {
+		return ""
+	}
+
+	if fields[0] != fmt.Sprintf("%d", info.ModTime().UnixNano()) ||
+		fields[1] != fmt.Sprintf("%d", info.Size()) {
+		return ""
+	}
+
+	return fields[2]
+}
+
+// cacheDigest records the verified digest of the specified file along
+// with its modification time and size, best effort.
+func cacheDigest(path string, info os.FileInfo, digest string) {
+	if err := os.MkdirAll(integrityCacheDir, 0700); err != nil {
+		return
+	}
+
+	contents := fmt.Sprintf("%d %d %s\n", info.ModTime().UnixNano(), info.Size(), digest)
+
+	writeFile(cachePath(path), contents, 0600)
+}
+
+// cachePath returns the cache file used for the specified artifact.
+func cachePath(path string) string {
+	return filepath.Join(integrityCacheDir, fmt.Sprintf("%x", sha256.Sum256([]byte(path))))
+}
+
+// verifyArtifactDigest checks that the SHA-256 digest of the specified
+// file matches the expected hex digest, consulting and maintaining the
+// digest cache.
+func verifyArtifactDigest(path, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if digest := cachedDigest(path, info); digest == expected {
+		return nil
+	}
+
+	digest, err := sha256File(path)
+	if err != nil {
+		return err
+	}
+
+	if digest != expected {
+		return fmt.Errorf("%v: digest mismatch: expected %v, got %v", path, expected, digest)
+	}
+
+	cacheDigest(path, info, digest)
+
+	return nil
+}
+
+// verifyGuestArtifacts verifies the guest kernel and image against the
+// digests recorded in the configuration file, if any. A mismatch means
+// the artifacts were tampered with (or updated without updating the
+// configuration) and must fail the pod creation.
+func verifyGuestArtifacts(runtimeConfig oci.RuntimeConfig) error {
+	artifacts := []struct {
+		description string
+		path        string
+		digest      string
+	}{
+		{"guest kernel", runtimeConfig.HypervisorConfig.KernelPath, kernelDigest},
+		{"guest image", runtimeConfig.HypervisorConfig.ImagePath, imageDigest},
+	}
+
+	for _, artifact := range artifacts {
+		if artifact.digest == "" {
+			continue
+		}
+
+		if err := verifyArtifactDigest(artifact.path, artifact.digest); err != nil {
+			return fmt.Errorf("%s failed integrity verification: %v", artifact.description, err)
+		}
+
+		ccLog.WithField("path", artifact.path).Debugf("%s integrity verified", artifact.description)
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSha256File(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	path := filepath.Join(tmpdir, "artifact")
+	contents := "hello world\n"
+
+	err = createFile(path, contents)
+	assert.NoError(err)
+
+	digest, err := sha256File(path)
+	assert.NoError(err)
+	assert.Equal(fmt.Sprintf("%x", sha256.Sum256([]byte(contents))), digest)
+
+	// missing file
+	_, err = sha256File(filepath.Join(tmpdir, "does-not-exist"))
+	assert.Error(err)
+}
+
+func TestVerifyArtifactDigest(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedIntegrityCacheDir := integrityCacheDir
+
+	defer func() {
+		integrityCacheDir = savedIntegrityCacheDir
+	}()
+
+	integrityCacheDir = filepath.Join(tmpdir, "cache")
+
+	path := filepath.Join(tmpdir, "artifact")
+	contents := "artifact data"
+
+	err = createFile(path, contents)
+	assert.NoError(err)
+
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte(contents)))
+
+	err = verifyArtifactDigest(path, digest)
+	assert.NoError(err)
+
+	// the digest is now cached
+	info, err := os.Stat(path)
+	assert.NoError(err)
+	assert.Equal(digest, cachedDigest(path, info))
+
+	// a second verification uses the cache
+	err = verifyArtifactDigest(path, digest)
+	assert.NoError(err)
+
+	// mismatching digest
+	err = verifyArtifactDigest(path, fmt.Sprintf("%x", sha256.Sum256([]byte("other"))))
+	assert.Error(err)
+
+	// changing the file invalidates the cache entry
+	err = createFile(path, contents+" tampered")
+	assert.NoError(err)
+
+	err = verifyArtifactDigest(path, digest)
+	assert.Error(err)
+}
+
+func TestVerifyGuestArtifacts(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedKernelDigest := kernelDigest
+	savedImageDigest := imageDigest
+	savedIntegrityCacheDir := integrityCacheDir
+
+	defer func() {
+		kernelDigest = savedKernelDigest
+		imageDigest = savedImageDigest
+		integrityCacheDir = savedIntegrityCacheDir
+	}()
+
+	integrityCacheDir = filepath.Join(tmpdir, "cache")
+
+	kernelPath := filepath.Join(tmpdir, "kernel")
+	imagePath := filepath.Join(tmpdir, "image")
+
+	assert.NoError(createFile(kernelPath, "kernel"))
+	assert.NoError(createFile(imagePath, "image"))
+
+	config := oci.RuntimeConfig{}
+	config.HypervisorConfig.KernelPath = kernelPath
+	config.HypervisorConfig.ImagePath = imagePath
+
+	// verification disabled
+	kernelDigest = ""
+	imageDigest = ""
+	assert.NoError(verifyGuestArtifacts(config))
+
+	kernelDigest = fmt.Sprintf("%x", sha256.Sum256([]byte("kernel")))
+	imageDigest = fmt.Sprintf("%x", sha256.Sum256([]byte("image")))
+	assert.NoError(verifyGuestArtifacts(config))
+
+	// tampered image
+	assert.NoError(createFile(imagePath, "not the image"))
+	assert.Error(verifyGuestArtifacts(config))
+}
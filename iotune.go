@@ -0,0 +1,86 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// ioPresetAnnotation is the annotation a pod can use to select an I/O
+// tuning preset, overriding the configuration file.
+const ioPresetAnnotation = ccAnnotationsPrefix + "io_preset"
+
+// ioTuningPreset is the I/O tuning preset from the [runtime] section
+// of the configuration file. An empty value leaves the guest defaults
+// untouched.
+var ioTuningPreset = ""
+
+// ioTuningPresets maps preset names to the guest sysctls they apply.
+// "throughput" batches writeback aggressively, which benchmarks and
+// bulk-I/O workloads want; "latency" keeps the amount of dirty data
+// small so individual writes complete predictably.
+var ioTuningPresets = map[string]map[string]string{
+	"throughput": {
+		"vm.dirty_ratio":            "40",
+		"vm.dirty_background_ratio": "10",
+		"vm.dirty_expire_centisecs": "6000",
+	},
+	"latency": {
+		"vm.dirty_ratio":            "10",
+		"vm.dirty_background_ratio": "3",
+	},
+}
+
+// ioPresetSysctls returns the guest sysctls implementing the I/O
+// tuning preset selected by the pod annotation or, failing that, the
+// configuration file.
+func ioPresetSysctls(ociSpec oci.CompatOCISpec) ([]guestSysctl, error) {
+	preset := ioTuningPreset
+
+	if value, ok := ociSpec.Annotations[ioPresetAnnotation]; ok && value != "" {
+		preset = value
+	}
+
+	if preset == "" {
+		return nil, nil
+	}
+
+	sysctls, ok := ioTuningPresets[preset]
+	if !ok {
+		return nil, fmt.Errorf("Unknown I/O tuning preset %q", preset)
+	}
+
+	// sort the keys for deterministic application
+	keys := []string{}
+	for key := range sysctls {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var result []guestSysctl
+
+	for _, key := range keys {
+		result = append(result, guestSysctl{
+			key:   key,
+			value: sysctls[key],
+		})
+	}
+
+	return result, nil
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIOPresetSysctls(t *testing.T) {
+	assert := assert.New(t)
+
+	savedIOTuningPreset := ioTuningPreset
+
+	defer func() {
+		ioTuningPreset = savedIOTuningPreset
+	}()
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no preset selected
+	ioTuningPreset = ""
+	sysctls, err := ioPresetSysctls(ociSpec)
+	assert.NoError(err)
+	assert.Empty(sysctls)
+
+	// preset from the configuration
+	ioTuningPreset = "latency"
+	sysctls, err = ioPresetSysctls(ociSpec)
+	assert.NoError(err)
+	assert.Equal([]guestSysctl{
+		{key: "vm.dirty_background_ratio", value: "3"},
+		{key: "vm.dirty_ratio", value: "10"},
+	}, sysctls)
+
+	// annotation overrides the configuration
+	ociSpec.Annotations = map[string]string{
+		ioPresetAnnotation: "throughput",
+	}
+
+	sysctls, err = ioPresetSysctls(ociSpec)
+	assert.NoError(err)
+	assert.Equal([]guestSysctl{
+		{key: "vm.dirty_background_ratio", value: "10"},
+		{key: "vm.dirty_expire_centisecs", value: "6000"},
+		{key: "vm.dirty_ratio", value: "40"},
+	}, sysctls)
+
+	// unknown preset
+	ociSpec.Annotations[ioPresetAnnotation] = "ludicrous-speed"
+	_, err = ioPresetSysctls(ociSpec)
+	assert.Error(err)
+}
@@ -106,6 +106,10 @@ var runtimeFlags = []cli.Flag{
 		Name:  showConfigPathsOption,
 		Usage: "show config file paths that will be checked for (in order)",
 	},
+	cli.BoolFlag{
+		Name:  "systemd-cgroup",
+		Usage: "enable systemd cgroup support, the OCI cgroupsPath is expected to be of the form \"slice:prefix:name\"",
+	},
 }
 
 // runtimeCommands is the list of supported command-line (sub-)
@@ -127,6 +131,10 @@ var runtimeCommands = []cli.Command{
 	// Clear Containers specific extensions
 	ccCheckCLICommand,
 	ccEnvCLICommand,
+	ccMetricsCLICommand,
+	ccReattachCLICommand,
+	ccShutdownAllCLICommand,
+	ccValidateCLICommand,
 }
 
 // runtimeBeforeSubcommands is the function to run before command-line
@@ -206,6 +214,8 @@ func beforeSubcommands(context *cli.Context) error {
 		return nil
 	}
 
+	systemdCgroup = context.GlobalBool("systemd-cgroup")
+
 	if path := context.GlobalString("log"); path != "" {
 		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND|os.O_SYNC, 0640)
 		if err != nil {
@@ -219,6 +229,9 @@ func beforeSubcommands(context *cli.Context) error {
 		// retain logrus's default.
 	case "json":
 		ccLog.Logger.Formatter = new(logrus.JSONFormatter)
+
+		// errors become machine-parsable too
+		jsonErrorFormat = true
 	default:
 		return fmt.Errorf("unknown log-format %q", context.GlobalString("log-format"))
 	}
@@ -245,7 +258,7 @@ func beforeSubcommands(context *cli.Context) error {
 
 	configFile, runtimeConfig, err := loadConfiguration(context.GlobalString(configFilePathOption), ignoreLogging)
 	if err != nil {
-		fatal(err)
+		fatal(configError(err))
 	}
 
 	args := strings.Join(context.Args(), " ")
@@ -356,11 +369,22 @@ func userWantsUsage(context *cli.Context) bool {
 	return false
 }
 
-// fatal prints the error's details exits the program.
+// jsonErrorFormat is set when JSON logging is selected so that errors
+// reported on stderr are machine-parsable as well.
+var jsonErrorFormat bool
+
+// fatal prints the error's details and exits the program with the exit
+// code matching the error class.
 func fatal(err error) {
 	ccLog.Error(err)
-	fmt.Fprintln(defaultErrorFile, err)
-	exit(1)
+
+	if jsonErrorFormat {
+		fmt.Fprintln(defaultErrorFile, errorJSON(err))
+	} else {
+		fmt.Fprintln(defaultErrorFile, err)
+	}
+
+	exit(errorExitCode(err))
 }
 
 type fatalWriter struct {
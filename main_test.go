@@ -685,6 +685,12 @@ func TestMainBeforeSubCommandsLoadConfigurationFail(t *testing.T) {
 
 	app := cli.NewApp()
 
+	savedJSONErrorFormat := jsonErrorFormat
+
+	defer func() {
+		jsonErrorFormat = savedJSONErrorFormat
+	}()
+
 	for _, logFormat := range []string{"json", "text"} {
 		set := flag.NewFlagSet("", 0)
 		set.Bool("debug", true, "")
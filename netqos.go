@@ -0,0 +1,203 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// annotations used to request network quality-of-service settings for
+// a pod. Rates are in kbit/s, the delay in milliseconds.
+const (
+	networkTxRateAnnotation    = ccAnnotationsPrefix + "network_tx_rate_kbits"
+	networkRxRateAnnotation    = ccAnnotationsPrefix + "network_rx_rate_kbits"
+	networkDelayAnnotation     = ccAnnotationsPrefix + "network_delay_ms"
+	networkInterfaceAnnotation = ccAnnotationsPrefix + "network_qos_interface"
+)
+
+// defaultQoSInterface is the interface inside the pod network
+// namespace the QoS settings are applied to unless the pod specifies
+// otherwise.
+const defaultQoSInterface = "eth0"
+
+// commands used to apply the QoS settings ("var" to allow tests to
+// modify them).
+var (
+	tcCmd      = "tc"
+	nsenterCmd = "nsenter"
+)
+
+// networkQoS holds the parsed per-pod network quality-of-service
+// settings.
+type networkQoS struct {
+	// transmit rate limit in kbit/s, zero for unlimited
+	txRateKbits uint64
+
+	// receive rate limit in kbit/s, zero for unlimited
+	rxRateKbits uint64
+
+	// artificial transmit delay in milliseconds, zero for none
+	delayMs uint64
+
+	// interface inside the pod network namespace to apply the
+	// settings to
+	netInterface string
+}
+
+// networkQoSFromAnnotations builds the network QoS settings requested
+// by the pod annotations. It returns nil if the pod does not request
+// any.
+func networkQoSFromAnnotations(ociSpec oci.CompatOCISpec) (*networkQoS, error) {
+	qos := &networkQoS{
+		netInterface: defaultQoSInterface,
+	}
+
+	found := false
+
+	values := map[string]*uint64{
+		networkTxRateAnnotation: &qos.txRateKbits,
+		networkRxRateAnnotation: &qos.rxRateKbits,
+		networkDelayAnnotation:  &qos.delayMs,
+	}
+
+	for annotation, target := range values {
+		value, ok := ociSpec.Annotations[annotation]
+		if !ok || value == "" {
+			continue
+		}
+
+		parsed, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || parsed == 0 {
+			return nil, fmt.Errorf("Annotation %v must be a positive integer, got %q", annotation, value)
+		}
+
+		*target = parsed
+		found = true
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	if netInterface := ociSpec.Annotations[networkInterfaceAnnotation]; netInterface != "" {
+		qos.netInterface = netInterface
+	}
+
+	return qos, nil
+}
+
+// networkQoSCommands returns the tc invocations implementing the
+// specified QoS settings: a tbf qdisc for the transmit rate, a netem
+// child qdisc for the delay and an ingress policer for the receive
+// rate.
+func (qos *networkQoS) networkQoSCommands() [][]string {
+	var commands [][]string
+
+	haveRootQdisc := false
+
+	if qos.txRateKbits != 0 {
+		commands = append(commands, []string{
+			tcCmd, "qdisc", "add", "dev", qos.netInterface, "root", "handle", "1:",
+			"tbf", "rate", fmt.Sprintf("%dkbit", qos.txRateKbits),
+			"burst", "32kbit", "latency", "400ms",
+		})
+
+		haveRootQdisc = true
+	}
+
+	if qos.delayMs != 0 {
+		command := []string{tcCmd, "qdisc", "add", "dev", qos.netInterface}
+
+		if haveRootQdisc {
+			command = append(command, "parent", "1:1")
+		} else {
+			command = append(command, "root")
+		}
+
+		command = append(command, "netem", "delay", fmt.Sprintf("%dms", qos.delayMs))
+
+		commands = append(commands, command)
+	}
+
+	if qos.rxRateKbits != 0 {
+		commands = append(commands,
+			[]string{
+				tcCmd, "qdisc", "add", "dev", qos.netInterface, "handle", "ffff:", "ingress",
+			},
+			[]string{
+				tcCmd, "filter", "add", "dev", qos.netInterface, "parent", "ffff:",
+				"protocol", "ip", "u32", "match", "u32", "0", "0",
+				"police", "rate", fmt.Sprintf("%dkbit", qos.rxRateKbits),
+				"burst", "32k", "drop", "flowid", ":1",
+			})
+	}
+
+	return commands
+}
+
+// getNetNsPath returns the path of the pod network namespace recorded
+// in the OCI spec, or an empty string if the spec does not provide
+// one.
+func getNetNsPath(ociSpec oci.CompatOCISpec) string {
+	if ociSpec.Linux == nil {
+		return ""
+	}
+
+	for _, ns := range ociSpec.Linux.Namespaces {
+		if ns.Type == specs.NetworkNamespace {
+			return ns.Path
+		}
+	}
+
+	return ""
+}
+
+// applyNetworkQoS applies the annotation-requested network QoS
+// settings to the pod's interface, entering the pod network namespace
+// to do so. Constrained-network pods need to fail their creation
+// rather than silently run unconstrained, so any tc failure is fatal.
+func applyNetworkQoS(ociSpec oci.CompatOCISpec) error {
+	qos, err := networkQoSFromAnnotations(ociSpec)
+	if err != nil {
+		return err
+	}
+
+	if qos == nil {
+		return nil
+	}
+
+	netNsPath := getNetNsPath(ociSpec)
+	if netNsPath == "" {
+		return fmt.Errorf("Cannot apply network QoS: no network namespace in OCI spec")
+	}
+
+	for _, command := range qos.networkQoSCommands() {
+		args := append([]string{"--net=" + netNsPath}, command...)
+
+		output, err := exec.Command(nsenterCmd, args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("Failed to apply network QoS (%v): %v: %s", command, err, output)
+		}
+	}
+
+	ccLog.WithField("interface", qos.netInterface).Info("network QoS applied")
+
+	return nil
+}
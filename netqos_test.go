@@ -0,0 +1,137 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkQoSFromAnnotations(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotations
+	qos, err := networkQoSFromAnnotations(ociSpec)
+	assert.NoError(err)
+	assert.Nil(qos)
+
+	ociSpec.Annotations = map[string]string{
+		networkTxRateAnnotation: "10000",
+		networkDelayAnnotation:  "50",
+	}
+
+	qos, err = networkQoSFromAnnotations(ociSpec)
+	assert.NoError(err)
+	assert.NotNil(qos)
+	assert.Equal(uint64(10000), qos.txRateKbits)
+	assert.Equal(uint64(0), qos.rxRateKbits)
+	assert.Equal(uint64(50), qos.delayMs)
+	assert.Equal(defaultQoSInterface, qos.netInterface)
+
+	// custom interface
+	ociSpec.Annotations[networkInterfaceAnnotation] = "net1"
+
+	qos, err = networkQoSFromAnnotations(ociSpec)
+	assert.NoError(err)
+	assert.Equal("net1", qos.netInterface)
+
+	// invalid values
+	for _, value := range []string{"-1", "0", "fast", "10.5"} {
+		ociSpec.Annotations = map[string]string{
+			networkRxRateAnnotation: value,
+		}
+
+		_, err = networkQoSFromAnnotations(ociSpec)
+		assert.Error(err, "value %q should be rejected", value)
+	}
+}
+
+func TestNetworkQoSCommands(t *testing.T) {
+	assert := assert.New(t)
+
+	qos := &networkQoS{
+		txRateKbits:  10000,
+		rxRateKbits:  20000,
+		delayMs:      50,
+		netInterface: "eth0",
+	}
+
+	commands := qos.networkQoSCommands()
+
+	// tbf, netem, ingress qdisc, ingress filter
+	assert.Len(commands, 4)
+
+	joined := []string{}
+	for _, command := range commands {
+		assert.Equal(tcCmd, command[0])
+		joined = append(joined, strings.Join(command, " "))
+	}
+
+	assert.Contains(joined[0], "tbf rate 10000kbit")
+	assert.Contains(joined[1], "parent 1:1")
+	assert.Contains(joined[1], "netem delay 50ms")
+	assert.Contains(joined[2], "ingress")
+	assert.Contains(joined[3], "police rate 20000kbit")
+
+	// without a tx limit the netem qdisc is attached to the root
+	qos = &networkQoS{
+		delayMs:      10,
+		netInterface: "eth0",
+	}
+
+	commands = qos.networkQoSCommands()
+	assert.Len(commands, 1)
+	assert.Contains(strings.Join(commands[0], " "), "root netem delay 10ms")
+}
+
+func TestGetNetNsPath(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no Linux section
+	assert.Equal("", getNetNsPath(ociSpec))
+
+	ociSpec.Linux = &specs.Linux{
+		Namespaces: []specs.LinuxNamespace{
+			{Type: specs.PIDNamespace},
+			{Type: specs.NetworkNamespace, Path: "/run/netns/foo"},
+		},
+	}
+
+	assert.Equal("/run/netns/foo", getNetNsPath(ociSpec))
+}
+
+func TestApplyNetworkQoS(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no annotations: nothing to do
+	assert.NoError(applyNetworkQoS(ociSpec))
+
+	ociSpec.Annotations = map[string]string{
+		networkDelayAnnotation: "50",
+	}
+
+	// QoS requested but no network namespace available
+	assert.Error(applyNetworkQoS(ociSpec))
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	goruntime "runtime"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// network tuning setting from the [runtime] section of the
+// configuration file. Without it, receive processing of a pod
+// interface is handled by a single CPU, which caps network throughput
+// around one core.
+var enableNetworkTuning = false
+
+// networkTuningCPUs returns the number of CPUs the pod network receive
+// processing should be spread over, derived from the container CPU
+// quota when one is set and from the host CPU count otherwise.
+func networkTuningCPUs(ociSpec oci.CompatOCISpec) int {
+	numCPUs := goruntime.NumCPU()
+
+	if ociSpec.Linux != nil &&
+		ociSpec.Linux.Resources != nil &&
+		ociSpec.Linux.Resources.CPU != nil &&
+		ociSpec.Linux.Resources.CPU.Quota != nil &&
+		ociSpec.Linux.Resources.CPU.Period != nil &&
+		*ociSpec.Linux.Resources.CPU.Quota > 0 &&
+		*ociSpec.Linux.Resources.CPU.Period > 0 {
+
+		quotaCPUs := int((*ociSpec.Linux.Resources.CPU.Quota +
+			int64(*ociSpec.Linux.Resources.CPU.Period) - 1) /
+			int64(*ociSpec.Linux.Resources.CPU.Period))
+
+		if quotaCPUs > 0 && quotaCPUs < numCPUs {
+			numCPUs = quotaCPUs
+		}
+	}
+
+	// the RPS mask below is limited to 64 CPUs
+	if numCPUs > 64 {
+		numCPUs = 64
+	}
+
+	return numCPUs
+}
+
+// rpsMask returns the hex CPU mask covering the specified number of
+// CPUs, in the format expected by rps_cpus.
+func rpsMask(numCPUs int) string {
+	if numCPUs >= 64 {
+		return fmt.Sprintf("%x", uint64(0xffffffffffffffff))
+	}
+
+	return fmt.Sprintf("%x", (uint64(1)<<uint(numCPUs))-1)
+}
+
+// applyNetworkTuning enables receive packet steering on the pod
+// interface so that receive processing is spread over the CPUs
+// available to the workload rather than being serialised on one core.
+// Tuning failures are logged but do not fail the pod creation: the pod
+// still works, just slower.
+func applyNetworkTuning(ociSpec oci.CompatOCISpec) {
+	if !enableNetworkTuning {
+		return
+	}
+
+	netNsPath := getNetNsPath(ociSpec)
+	if netNsPath == "" {
+		return
+	}
+
+	mask := rpsMask(networkTuningCPUs(ociSpec))
+
+	// every rx queue of the default interface gets the same mask
+	script := fmt.Sprintf(
+		"for q in /sys/class/net/%s/queues/rx-*/rps_cpus; do echo %s > $q; done",
+		defaultQoSInterface, mask)
+
+	output, err := exec.Command(nsenterCmd, "--net="+netNsPath, "sh", "-c", script).CombinedOutput()
+	if err != nil {
+		ccLog.WithError(err).WithField("output", string(output)).Warn("failed to tune pod network")
+		return
+	}
+
+	ccLog.WithField("rps-mask", mask).Info("pod network tuned")
+}
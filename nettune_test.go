@@ -0,0 +1,67 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	goruntime "runtime"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNetworkTuningCPUs(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	hostCPUs := goruntime.NumCPU()
+	if hostCPUs > 64 {
+		hostCPUs = 64
+	}
+
+	// without a CPU quota, all host CPUs are used
+	assert.Equal(hostCPUs, networkTuningCPUs(ociSpec))
+
+	quota := int64(150000)
+	period := uint64(100000)
+
+	ociSpec.Linux = &specs.Linux{
+		Resources: &specs.LinuxResources{
+			CPU: &specs.LinuxCPU{
+				Quota:  &quota,
+				Period: &period,
+			},
+		},
+	}
+
+	// 1.5 CPUs rounds up to 2 (unless the host has fewer)
+	expected := 2
+	if hostCPUs < expected {
+		expected = hostCPUs
+	}
+
+	assert.Equal(expected, networkTuningCPUs(ociSpec))
+}
+
+func TestRpsMask(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("1", rpsMask(1))
+	assert.Equal("3", rpsMask(2))
+	assert.Equal("f", rpsMask(4))
+	assert.Equal("ffffffffffffffff", rpsMask(64))
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// The guest image is mapped into the VM as an NVDIMM device and
+// mounted with DAX, so guest page cache pages are backed directly by
+// the host page cache instead of being duplicated. This needs nvdimm
+// support in the hypervisor; without it pod creation fails deep inside
+// qemu with an unhelpful message, so the capability is probed up front
+// by the check command.
+
+// nvdimmDeviceName is the qemu device the image mapping relies on.
+const nvdimmDeviceName = "nvdimm"
+
+// probeNVDIMMSupport returns true if the specified hypervisor supports
+// NVDIMM devices, based on the device list it advertises.
+func probeNVDIMMSupport(hypervisorPath string) (bool, error) {
+	output, err := exec.Command(hypervisorPath, "-device", "help").CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("cannot query hypervisor %v devices: %v", hypervisorPath, err)
+	}
+
+	for _, line := range strings.Split(string(output), "\n") {
+		if strings.Contains(line, `"`+nvdimmDeviceName+`"`) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// checkNVDIMMSupport reports whether the specified hypervisor can map
+// the guest image via NVDIMM with DAX. Lack of support is only a
+// warning at check time, but explains why pods would fail to boot.
+func checkNVDIMMSupport(hypervisorPath string) error {
+	hypervisorPath, err := resolvePath(hypervisorPath)
+	if err != nil {
+		return err
+	}
+
+	supported, err := probeNVDIMMSupport(hypervisorPath)
+	if err != nil {
+		return err
+	}
+
+	fields := logrus.Fields{
+		"hypervisor": hypervisorPath,
+		"device":     nvdimmDeviceName,
+	}
+
+	if !supported {
+		ccLog.WithFields(fields).Warn("hypervisor cannot map the guest image via NVDIMM/DAX, pods will fail to boot")
+		return nil
+	}
+
+	ccLog.WithFields(fields).Info("hypervisor supports NVDIMM/DAX image mapping")
+
+	return nil
+}
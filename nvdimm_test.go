@@ -0,0 +1,88 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createFakeHypervisor creates a script that mimics "qemu -device help"
+// output, advertising the specified devices.
+func createFakeHypervisor(dir string, devices []string) (string, error) {
+	script := "#!/bin/sh\n"
+
+	for _, device := range devices {
+		script += `echo 'name "` + device + `"'` + "\n"
+	}
+
+	path := filepath.Join(dir, "hypervisor")
+
+	return path, ioutil.WriteFile(path, []byte(script), 0755)
+}
+
+func TestProbeNVDIMMSupport(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	// hypervisor advertising nvdimm
+	hypervisor, err := createFakeHypervisor(tmpdir, []string{"virtio-blk-pci", "nvdimm"})
+	assert.NoError(err)
+
+	supported, err := probeNVDIMMSupport(hypervisor)
+	assert.NoError(err)
+	assert.True(supported)
+
+	// hypervisor without nvdimm
+	hypervisor, err = createFakeHypervisor(tmpdir, []string{"virtio-blk-pci"})
+	assert.NoError(err)
+
+	supported, err = probeNVDIMMSupport(hypervisor)
+	assert.NoError(err)
+	assert.False(supported)
+
+	// missing hypervisor
+	_, err = probeNVDIMMSupport(filepath.Join(tmpdir, "does-not-exist"))
+	assert.Error(err)
+}
+
+func TestCheckNVDIMMSupport(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	hypervisor, err := createFakeHypervisor(tmpdir, []string{"nvdimm"})
+	assert.NoError(err)
+
+	assert.NoError(checkNVDIMMSupport(hypervisor))
+
+	// lack of support only warns
+	hypervisor, err = createFakeHypervisor(tmpdir, []string{"virtio-blk-pci"})
+	assert.NoError(err)
+
+	assert.NoError(checkNVDIMMSupport(hypervisor))
+
+	// unresolvable hypervisor path
+	assert.Error(checkNVDIMMSupport(filepath.Join(tmpdir, "does-not-exist")))
+}
@@ -200,9 +200,20 @@ func processCgroupsPathForResource(ociSpec oci.CompatOCISpec, resource string, i
 		return "", fmt.Errorf("get CgroupsDirPath error: %s", err)
 	}
 
+	specCgroupsPath := ociSpec.Linux.CgroupsPath
+
+	// With systemd cgroups the spec carries a "slice:prefix:name"
+	// triplet (podman/conmon invocations) instead of a path.
+	if systemdCgroup {
+		specCgroupsPath, err = expandSystemdCgroupPath(specCgroupsPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	// Relative cgroups path provided.
-	if filepath.IsAbs(ociSpec.Linux.CgroupsPath) == false {
-		return filepath.Join(cgroupsDirPath, resource, ociSpec.Linux.CgroupsPath), nil
+	if filepath.IsAbs(specCgroupsPath) == false {
+		return filepath.Join(cgroupsDirPath, resource, specCgroupsPath), nil
 	}
 
 	// Absolute cgroups path provided.
@@ -220,7 +231,7 @@ func processCgroupsPathForResource(ociSpec oci.CompatOCISpec, resource string, i
 		// According to the OCI spec, an absolute path should be
 		// interpreted as relative to the system cgroup mount point
 		// when there is no cgroup mount point.
-		return filepath.Join(cgroupsDirPath, resource, ociSpec.Linux.CgroupsPath), nil
+		return filepath.Join(cgroupsDirPath, resource, specCgroupsPath), nil
 	}
 
 	if cgroupMount.Destination == "" {
@@ -244,7 +255,7 @@ func processCgroupsPathForResource(ociSpec oci.CompatOCISpec, resource string, i
 
 	ccLog.WithFields(fields).Info("path mounted")
 
-	return filepath.Join(cgroupPath, ociSpec.Linux.CgroupsPath), nil
+	return filepath.Join(cgroupPath, specCgroupsPath), nil
 }
 
 func isCgroupMounted(cgroupPath string) bool {
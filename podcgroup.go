@@ -0,0 +1,272 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// enableSandboxCgroup is set from the runtime configuration. When
+// enabled, the host processes backing a pod (hypervisor, shim, proxy)
+// are placed in a per-pod cgroup with limits derived from the workload
+// limits plus a configurable overhead, so the VM cost shows up in host
+// resource accounting instead of escaping it.
+var enableSandboxCgroup bool
+
+// defaultSandboxMemOverheadMB is the default amount of host memory
+// reserved on top of the workload memory limits for the VM itself
+// (guest kernel, qemu heap, shim and proxy).
+const defaultSandboxMemOverheadMB = 350
+
+// defaultSandboxCPUOverheadPct is the default CPU overhead granted on
+// top of the workload CPU quota, as a percentage of one host CPU.
+const defaultSandboxCPUOverheadPct = 10
+
+// sandboxMemOverheadMB and sandboxCPUOverheadPct are set from the
+// runtime configuration.
+var sandboxMemOverheadMB = int64(defaultSandboxMemOverheadMB)
+var sandboxCPUOverheadPct = int64(defaultSandboxCPUOverheadPct)
+
+// sandboxCgroupPeriod is the CFS period the pod cgroup quota is
+// expressed in, in microseconds.
+const sandboxCgroupPeriod = 100000
+
+// cgroupsFsDir is the mount point of the cgroup filesystem hierarchies
+// ("var" to allow tests to modify it).
+var cgroupsFsDir = "/sys/fs/cgroup"
+
+// sandboxCgroupAnnotation carries the pod cgroup path in the state
+// command output.
+const sandboxCgroupAnnotation = ccAnnotationsPrefix + "sandbox_cgroup"
+
+// podCgroupPath returns the per-pod cgroup directory for the specified
+// controller.
+func podCgroupPath(controller, podID string) string {
+	return filepath.Join(cgroupsFsDir, controller, name, podID)
+}
+
+// specMemoryLimit returns the memory limit of the specified spec in
+// bytes, zero if none is set.
+func specMemoryLimit(ociSpec oci.CompatOCISpec) int64 {
+	if ociSpec.Linux == nil ||
+		ociSpec.Linux.Resources == nil ||
+		ociSpec.Linux.Resources.Memory == nil ||
+		ociSpec.Linux.Resources.Memory.Limit == nil {
+		return 0
+	}
+
+	return *ociSpec.Linux.Resources.Memory.Limit
+}
+
+// specCPUQuota returns the CPU quota of the specified spec normalised
+// to sandboxCgroupPeriod, zero if none is set.
+func specCPUQuota(ociSpec oci.CompatOCISpec) int64 {
+	if ociSpec.Linux == nil ||
+		ociSpec.Linux.Resources == nil ||
+		ociSpec.Linux.Resources.CPU == nil ||
+		ociSpec.Linux.Resources.CPU.Quota == nil ||
+		*ociSpec.Linux.Resources.CPU.Quota <= 0 {
+		return 0
+	}
+
+	period := int64(sandboxCgroupPeriod)
+	if ociSpec.Linux.Resources.CPU.Period != nil && *ociSpec.Linux.Resources.CPU.Period > 0 {
+		period = int64(*ociSpec.Linux.Resources.CPU.Period)
+	}
+
+	return *ociSpec.Linux.Resources.CPU.Quota * sandboxCgroupPeriod / period
+}
+
+// readPodCgroupValue reads an integer control file from the pod cgroup,
+// returning zero if the file does not exist.
+func readPodCgroupValue(controller, podID, file string) (int64, error) {
+	contents, err := getFileContents(filepath.Join(podCgroupPath(controller, podID), file))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+
+	return strconv.ParseInt(strings.TrimSpace(contents), 10, 64)
+}
+
+// writePodCgroupValue writes an integer control file in the pod cgroup.
+func writePodCgroupValue(controller, podID, file string, value int64) error {
+	path := filepath.Join(podCgroupPath(controller, podID), file)
+	return writeFile(path, strconv.FormatInt(value, 10), 0600)
+}
+
+// createPodCgroup creates the per-pod memory and cpu cgroups and moves
+// the host processes backing the pod into them. The shim process ID is
+// known to the caller; the hypervisor and proxy are found by scanning
+// the proc filesystem, and failing to find them is only logged since
+// the proxy may legitimately be shared or not running yet.
+func createPodCgroup(podID string, shimPid int) error {
+	for _, controller := range []string{"memory", "cpu"} {
+		if err := os.MkdirAll(podCgroupPath(controller, podID), cgroupsDirMode); err != nil {
+			return err
+		}
+	}
+
+	pids := []int{}
+
+	if shimPid != 0 {
+		pids = append(pids, shimPid)
+	}
+
+	if vmPid := findVMPid(podID); vmPid != 0 {
+		pids = append(pids, vmPid)
+	} else {
+		ccLog.WithField("pod", podID).Warn("VM process not found, not added to pod cgroup")
+	}
+
+	if proxyPid := findPodProxyPid(podID); proxyPid != 0 {
+		pids = append(pids, proxyPid)
+	}
+
+	for _, controller := range []string{"memory", "cpu"} {
+		procsFile := filepath.Join(podCgroupPath(controller, podID), cgroupsProcsFile)
+
+		for _, pid := range pids {
+			if err := writeFile(procsFile, strconv.Itoa(pid), os.FileMode(0600)); err != nil {
+				ccLog.WithError(err).WithField("pid", pid).Warn("cannot move process into pod cgroup")
+			}
+		}
+	}
+
+	return nil
+}
+
+// findPodProxyPid scans the proc filesystem for the proxy instance
+// serving the specified pod, recognised by the pod ID appearing on its
+// command line (the per-pod proxy socket lives below a pod-specific
+// run directory). It returns zero if no such process is found.
+func findPodProxyPid(podID string) int {
+	proxyName := filepath.Base(defaultProxyPath)
+
+	entries, err := ioutil.ReadDir(procDir)
+	if err != nil {
+		return 0
+	}
+
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		contents, err := getFileContents(filepath.Join(procDir, entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+
+		cmdline := strings.Replace(contents, "\x00", " ", -1)
+
+		if !strings.Contains(cmdline, podID) {
+			continue
+		}
+
+		args := strings.Fields(cmdline)
+		if len(args) == 0 {
+			continue
+		}
+
+		if filepath.Base(args[0]) == proxyName {
+			return pid
+		}
+	}
+
+	return 0
+}
+
+// growPodCgroup raises the pod cgroup limits to account for the
+// specified container. The memory limit is the running sum of the
+// container limits plus the configured overhead; the CPU quota is the
+// sum of the container quotas plus the configured fraction of one CPU.
+// Containers without limits leave the corresponding controller
+// unconstrained.
+func growPodCgroup(ociSpec oci.CompatOCISpec, podID string) error {
+	if memLimit := specMemoryLimit(ociSpec); memLimit > 0 {
+		overhead := sandboxMemOverheadMB << 20
+
+		current, err := readPodCgroupValue("memory", podID, "memory.limit_in_bytes")
+		if err != nil {
+			return err
+		}
+
+		// workload sum so far: the recorded limit minus the overhead,
+		// unless no limit has been applied yet (the kernel reports a
+		// huge sentinel value for unlimited cgroups).
+		sum := int64(0)
+		if current > 0 && current < (1<<62) {
+			sum = current - overhead
+		}
+
+		if err := writePodCgroupValue("memory", podID, "memory.limit_in_bytes", sum+memLimit+overhead); err != nil {
+			return err
+		}
+	}
+
+	if quota := specCPUQuota(ociSpec); quota > 0 {
+		if err := writePodCgroupValue("cpu", podID, "cpu.cfs_period_us", sandboxCgroupPeriod); err != nil {
+			return err
+		}
+
+		current, err := readPodCgroupValue("cpu", podID, "cpu.cfs_quota_us")
+		if err != nil {
+			return err
+		}
+
+		overhead := sandboxCPUOverheadPct * sandboxCgroupPeriod / 100
+
+		sum := int64(0)
+		if current > 0 {
+			sum = current - overhead
+		}
+
+		if err := writePodCgroupValue("cpu", podID, "cpu.cfs_quota_us", sum+quota+overhead); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// removePodCgroup removes the per-pod cgroups once the pod is deleted.
+// The directories can only be removed when empty, so failures are
+// reported to the caller for logging rather than treated as fatal.
+func removePodCgroup(podID string) error {
+	var firstErr error
+
+	for _, controller := range []string{"memory", "cpu"} {
+		path := podCgroupPath(controller, podID)
+
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("cannot remove pod cgroup %v: %v", path, err)
+			}
+		}
+	}
+
+	return firstErr
+}
@@ -0,0 +1,145 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func specWithLimits(memLimit int64, cpuQuota int64, cpuPeriod uint64) oci.CompatOCISpec {
+	spec := oci.CompatOCISpec{}
+	spec.Linux = &specs.Linux{
+		Resources: &specs.LinuxResources{},
+	}
+
+	if memLimit != 0 {
+		spec.Linux.Resources.Memory = &specs.LinuxMemory{
+			Limit: &memLimit,
+		}
+	}
+
+	if cpuQuota != 0 {
+		spec.Linux.Resources.CPU = &specs.LinuxCPU{
+			Quota:  &cpuQuota,
+			Period: &cpuPeriod,
+		}
+	}
+
+	return spec
+}
+
+func TestSpecMemoryLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int64(0), specMemoryLimit(oci.CompatOCISpec{}))
+	assert.Equal(int64(0), specMemoryLimit(specWithLimits(0, 0, 0)))
+	assert.Equal(int64(1<<30), specMemoryLimit(specWithLimits(1<<30, 0, 0)))
+}
+
+func TestSpecCPUQuota(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(int64(0), specCPUQuota(oci.CompatOCISpec{}))
+	assert.Equal(int64(0), specCPUQuota(specWithLimits(0, 0, 0)))
+
+	// quota already expressed against the pod period
+	assert.Equal(int64(50000), specCPUQuota(specWithLimits(0, 50000, 100000)))
+
+	// quota normalised from a different period
+	assert.Equal(int64(50000), specCPUQuota(specWithLimits(0, 25000, 50000)))
+}
+
+func TestGrowPodCgroup(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedCgroupsFsDir := cgroupsFsDir
+
+	defer func() {
+		cgroupsFsDir = savedCgroupsFsDir
+	}()
+
+	cgroupsFsDir = tmpdir
+
+	const podID = "pod"
+
+	for _, controller := range []string{"memory", "cpu"} {
+		err = os.MkdirAll(podCgroupPath(controller, podID), cgroupsDirMode)
+		assert.NoError(err)
+	}
+
+	memOverhead := sandboxMemOverheadMB << 20
+	cpuOverhead := sandboxCPUOverheadPct * sandboxCgroupPeriod / 100
+
+	// first container: limit plus overhead
+	err = growPodCgroup(specWithLimits(1<<30, 50000, 100000), podID)
+	assert.NoError(err)
+
+	limit, err := readPodCgroupValue("memory", podID, "memory.limit_in_bytes")
+	assert.NoError(err)
+	assert.Equal(int64(1<<30)+memOverhead, limit)
+
+	quota, err := readPodCgroupValue("cpu", podID, "cpu.cfs_quota_us")
+	assert.NoError(err)
+	assert.Equal(int64(50000)+cpuOverhead, quota)
+
+	// second container: limits accumulate, overhead counted once
+	err = growPodCgroup(specWithLimits(1<<29, 25000, 100000), podID)
+	assert.NoError(err)
+
+	limit, err = readPodCgroupValue("memory", podID, "memory.limit_in_bytes")
+	assert.NoError(err)
+	assert.Equal(int64(1<<30)+int64(1<<29)+memOverhead, limit)
+
+	quota, err = readPodCgroupValue("cpu", podID, "cpu.cfs_quota_us")
+	assert.NoError(err)
+	assert.Equal(int64(75000)+cpuOverhead, quota)
+
+	period, err := readPodCgroupValue("cpu", podID, "cpu.cfs_period_us")
+	assert.NoError(err)
+	assert.Equal(int64(sandboxCgroupPeriod), period)
+
+	// a container without limits leaves the cgroup untouched
+	err = growPodCgroup(oci.CompatOCISpec{}, podID)
+	assert.NoError(err)
+
+	limit, err = readPodCgroupValue("memory", podID, "memory.limit_in_bytes")
+	assert.NoError(err)
+	assert.Equal(int64(1<<30)+int64(1<<29)+memOverhead, limit)
+
+	// on a real cgroup filesystem the control files vanish with the
+	// directory; mimic that here before removing the pod cgroups
+	for _, file := range []string{
+		podCgroupPath("memory", podID) + "/memory.limit_in_bytes",
+		podCgroupPath("cpu", podID) + "/cpu.cfs_quota_us",
+		podCgroupPath("cpu", podID) + "/cpu.cfs_period_us",
+	} {
+		assert.NoError(os.Remove(file))
+	}
+
+	assert.NoError(removePodCgroup(podID))
+
+	// removing an already removed cgroup is not an error
+	assert.NoError(removePodCgroup(podID))
+}
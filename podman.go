@@ -0,0 +1,93 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Daemonless callers (podman via conmon) drive the runtime directly:
+// conmon passes the console master back over the --console-socket
+// already supported by the create command and reads the container PID
+// from the --pid-file written last during create. The remaining gap is
+// the --systemd-cgroup flag those callers pass, under which the OCI
+// cgroupsPath is not a filesystem path but a systemd
+// "slice:prefix:name" triplet.
+
+// systemdCgroup is set by the --systemd-cgroup global option.
+var systemdCgroup bool
+
+// systemdSliceToPath expands a systemd slice unit name into its cgroup
+// filesystem path: dashes denote the hierarchy, so "a-b.slice" lives
+// below "a.slice".
+func systemdSliceToPath(slice string) (string, error) {
+	unit := strings.TrimSuffix(slice, ".slice")
+	if unit == slice || unit == "" || strings.ContainsAny(unit, "/.") {
+		return "", fmt.Errorf("Invalid systemd slice %q", slice)
+	}
+
+	path := ""
+	prefix := ""
+
+	for _, element := range strings.Split(unit, "-") {
+		if element == "" {
+			return "", fmt.Errorf("Invalid systemd slice %q", slice)
+		}
+
+		path = filepath.Join(path, prefix+element+".slice")
+		prefix += element + "-"
+	}
+
+	return path, nil
+}
+
+// expandSystemdCgroupPath translates the "slice:prefix:name" form of
+// cgroupsPath used with --systemd-cgroup into the relative cgroup
+// filesystem path the rest of the cgroup handling expects. A path
+// without the triplet syntax is returned unchanged so specs using
+// plain paths keep working regardless of the flag.
+func expandSystemdCgroupPath(cgroupsPath string) (string, error) {
+	if !strings.Contains(cgroupsPath, ":") {
+		return cgroupsPath, nil
+	}
+
+	parts := strings.Split(cgroupsPath, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("Invalid systemd cgroupsPath %q (expected slice:prefix:name)", cgroupsPath)
+	}
+
+	slice := parts[0]
+	if slice == "" {
+		slice = "system.slice"
+	}
+
+	slicePath, err := systemdSliceToPath(slice)
+	if err != nil {
+		return "", err
+	}
+
+	if parts[2] == "" {
+		return "", fmt.Errorf("Invalid systemd cgroupsPath %q (empty name)", cgroupsPath)
+	}
+
+	scope := parts[2] + ".scope"
+	if parts[1] != "" {
+		scope = parts[1] + "-" + scope
+	}
+
+	return filepath.Join(slicePath, scope), nil
+}
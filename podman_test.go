@@ -0,0 +1,68 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemdSliceToPath(t *testing.T) {
+	assert := assert.New(t)
+
+	path, err := systemdSliceToPath("machine.slice")
+	assert.NoError(err)
+	assert.Equal("machine.slice", path)
+
+	path, err = systemdSliceToPath("machine-libpod.slice")
+	assert.NoError(err)
+	assert.Equal("machine.slice/machine-libpod.slice", path)
+
+	for _, invalid := range []string{"", "machine", ".slice", "machine-.slice", "a/b.slice"} {
+		_, err = systemdSliceToPath(invalid)
+		assert.Error(err, "slice %q", invalid)
+	}
+}
+
+func TestExpandSystemdCgroupPath(t *testing.T) {
+	assert := assert.New(t)
+
+	// plain paths pass through untouched
+	for _, path := range []string{"", "/foo/bar", "foo"} {
+		expanded, err := expandSystemdCgroupPath(path)
+		assert.NoError(err)
+		assert.Equal(path, expanded)
+	}
+
+	expanded, err := expandSystemdCgroupPath("machine.slice:libpod:deadbeef")
+	assert.NoError(err)
+	assert.Equal("machine.slice/libpod-deadbeef.scope", expanded)
+
+	// empty slice defaults to system.slice
+	expanded, err = expandSystemdCgroupPath(":libpod:deadbeef")
+	assert.NoError(err)
+	assert.Equal("system.slice/libpod-deadbeef.scope", expanded)
+
+	// empty prefix is allowed
+	expanded, err = expandSystemdCgroupPath("machine.slice::deadbeef")
+	assert.NoError(err)
+	assert.Equal("machine.slice/deadbeef.scope", expanded)
+
+	for _, invalid := range []string{"a:b", "a:b:c:d", "notaslice:libpod:id", "machine.slice:libpod:"} {
+		_, err = expandSystemdCgroupPath(invalid)
+		assert.Error(err, "cgroupsPath %q", invalid)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli"
+)
+
+var ccReattachCLICommand = cli.Command{
+	Name:  reattachCmd,
+	Usage: "re-establish the shim connection to a still-running pod VM",
+	ArgsUsage: `<container-id>
+
+   <container-id> is the name for the instance of the container.`,
+	Description: `A restart of the host container daemon kills the shim processes but
+   leaves the pod VMs running. This command respawns the shim for the
+   specified container, reconnecting it to the proxy of the (still
+   running) VM, instead of the container being reported as lost.`,
+	Action: func(context *cli.Context) error {
+		runtimeConfig, ok := context.App.Metadata["runtimeConfig"].(oci.RuntimeConfig)
+		if !ok {
+			return errors.New("invalid runtime config")
+		}
+
+		containerID := context.Args().First()
+
+		status, podID, err := getExistingContainerInfo(containerID)
+		if err != nil {
+			return err
+		}
+
+		pid, err := reattachShim(runtimeConfig, podID, status.ID)
+		if err != nil {
+			return err
+		}
+
+		ccLog.WithFields(logrus.Fields{
+			"container": status.ID,
+			"pid":       pid,
+		}).Info("shim reattached")
+
+		return nil
+	},
+}
+
+// processAlive returns true if the specified process still exists.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+
+	return syscall.Kill(pid, syscall.Signal(0)) == nil
+}
+
+// reattachShim respawns the shim for the specified container if it
+// died (typically because the host container daemon restarted) while
+// the pod VM kept running. It returns the PID of the serving shim,
+// which is unchanged if the original shim is still alive.
+func reattachShim(runtimeConfig oci.RuntimeConfig, podID, containerID string) (int, error) {
+	processJSON := filepath.Join(podRunStoragePath, podID, containerID, "process.json")
+
+	contents, err := getFileContents(processJSON)
+	if err != nil {
+		return -1, err
+	}
+
+	var process vc.Process
+	if err := json.Unmarshal([]byte(contents), &process); err != nil {
+		return -1, err
+	}
+
+	if process.Token == "" {
+		return -1, fmt.Errorf("no execution token recorded for container %v", containerID)
+	}
+
+	if processAlive(process.Pid) {
+		// nothing to reattach
+		return process.Pid, nil
+	}
+
+	if findVMPid(podID) == 0 {
+		return -1, fmt.Errorf("pod %v VM is no longer running, cannot reattach", podID)
+	}
+
+	shimConfig, ok := runtimeConfig.ShimConfig.(vc.ShimConfig)
+	if !ok || shimConfig.Path == "" {
+		return -1, errors.New("invalid shim configuration")
+	}
+
+	proxyURL := fmt.Sprintf("unix://%s", filepath.Join(podRunStoragePath, podID, "proxy.sock"))
+
+	cmd := exec.Command(shimConfig.Path, "-c", containerID, "-t", process.Token, "-u", proxyURL)
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		// the shim must survive this command exiting
+		Setsid: true,
+	}
+
+	if err := cmd.Start(); err != nil {
+		return -1, err
+	}
+
+	process.Pid = cmd.Process.Pid
+
+	bytes, err := json.Marshal(process)
+	if err != nil {
+		return -1, err
+	}
+
+	if err := writeFile(processJSON, string(bytes), 0600); err != nil {
+		return -1, err
+	}
+
+	return process.Pid, nil
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProcessAlive(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(processAlive(os.Getpid()))
+	assert.False(processAlive(0))
+	assert.False(processAlive(-1))
+}
+
+func writeProcessJSON(t *testing.T, podID, containerID string, process vc.Process) string {
+	containerDir := filepath.Join(podRunStoragePath, podID, containerID)
+
+	err := os.MkdirAll(containerDir, testDirMode)
+	assert.NoError(t, err)
+
+	bytes, err := json.Marshal(process)
+	assert.NoError(t, err)
+
+	processJSON := filepath.Join(containerDir, "process.json")
+
+	err = writeFile(processJSON, string(bytes), 0600)
+	assert.NoError(t, err)
+
+	return processJSON
+}
+
+func TestReattachShim(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir(testDir, "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+	savedProcDir := procDir
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+		procDir = savedProcDir
+	}()
+
+	podRunStoragePath = filepath.Join(tmpdir, "run")
+	procDir = filepath.Join(tmpdir, "proc")
+
+	runtimeConfig := oci.RuntimeConfig{
+		ShimConfig: vc.ShimConfig{
+			Path: "/bin/true",
+		},
+	}
+
+	// no state recorded at all
+	_, err = reattachShim(runtimeConfig, testPodID, testContainerID)
+	assert.Error(err)
+
+	// missing token
+	writeProcessJSON(t, testPodID, testContainerID, vc.Process{})
+
+	_, err = reattachShim(runtimeConfig, testPodID, testContainerID)
+	assert.Error(err)
+
+	// shim still alive: its PID is returned unchanged
+	writeProcessJSON(t, testPodID, testContainerID, vc.Process{
+		Token: "token",
+		Pid:   os.Getpid(),
+	})
+
+	pid, err := reattachShim(runtimeConfig, testPodID, testContainerID)
+	assert.NoError(err)
+	assert.Equal(os.Getpid(), pid)
+
+	// dead shim and dead VM: reattaching is impossible
+	writeProcessJSON(t, testPodID, testContainerID, vc.Process{
+		Token: "token",
+		Pid:   -1,
+	})
+
+	_, err = reattachShim(runtimeConfig, testPodID, testContainerID)
+	assert.Error(err)
+
+	// dead shim but live VM: a new shim is spawned and recorded
+	err = os.MkdirAll(procDir, testDirMode)
+	assert.NoError(err)
+
+	err = createFakeVMProc(procDir, 999, testPodID, 4096)
+	assert.NoError(err)
+
+	processJSON := writeProcessJSON(t, testPodID, testContainerID, vc.Process{
+		Token: "token",
+		Pid:   -1,
+	})
+
+	pid, err = reattachShim(runtimeConfig, testPodID, testContainerID)
+	assert.NoError(err)
+	assert.NotEqual(-1, pid)
+
+	contents, err := getFileContents(processJSON)
+	assert.NoError(err)
+
+	var process vc.Process
+	assert.NoError(json.Unmarshal([]byte(contents), &process))
+	assert.Equal(pid, process.Pid)
+	assert.Equal("token", process.Token)
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	"github.com/sirupsen/logrus"
+)
+
+// handleContainerRestart checks whether a previous incarnation of the
+// specified container exists in the (still running) pod and, if it has
+// exited, deletes it so the new incarnation can be created against the
+// existing VM. Without this, re-creating a crashed container with the
+// same ID fails on the leftover state, pushing orchestrators to tear
+// down the whole sandbox and boot a fresh VM on every crash loop
+// iteration. It returns true if a previous incarnation was cleared.
+func handleContainerRestart(podID, containerID string) (bool, error) {
+	status, err := vci.StatusContainer(podID, containerID)
+	if err != nil {
+		// no previous incarnation, nothing to do
+		return false, nil
+	}
+
+	if oci.StateToOCIState(status.State) == oci.StateRunning {
+		return false, fmt.Errorf("Container %v is still running in pod %v", containerID, podID)
+	}
+
+	if _, err := vci.DeleteContainer(podID, containerID); err != nil {
+		return false, err
+	}
+
+	ccLog.WithFields(logrus.Fields{
+		"pod":       podID,
+		"container": containerID,
+	}).Info("cleared exited container, reusing pod VM for restart")
+
+	return true, nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"testing"
+
+	vc "github.com/kata-containers/runtime/virtcontainers"
+	"github.com/kata-containers/runtime/virtcontainers/pkg/vcmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandleContainerRestart(t *testing.T) {
+	assert := assert.New(t)
+
+	// no previous incarnation
+	cleared, err := handleContainerRestart(testPodID, testContainerID)
+	assert.NoError(err)
+	assert.False(cleared)
+
+	deleted := false
+
+	testingImpl.StatusContainerFunc = func(podID, containerID string) (vc.ContainerStatus, error) {
+		return vc.ContainerStatus{
+			ID: testContainerID,
+			State: vc.State{
+				State: vc.StateStopped,
+			},
+		}, nil
+	}
+
+	testingImpl.DeleteContainerFunc = func(podID, containerID string) (vc.VCContainer, error) {
+		deleted = true
+		return &vcmock.Container{}, nil
+	}
+
+	defer func() {
+		testingImpl.StatusContainerFunc = nil
+		testingImpl.DeleteContainerFunc = nil
+	}()
+
+	// exited previous incarnation is cleared
+	cleared, err = handleContainerRestart(testPodID, testContainerID)
+	assert.NoError(err)
+	assert.True(cleared)
+	assert.True(deleted)
+
+	// a running previous incarnation is an error
+	testingImpl.StatusContainerFunc = func(podID, containerID string) (vc.ContainerStatus, error) {
+		return vc.ContainerStatus{
+			ID: testContainerID,
+			State: vc.State{
+				State: vc.StateRunning,
+			},
+		}, nil
+	}
+
+	_, err = handleContainerRestart(testPodID, testContainerID)
+	assert.Error(err)
+
+	// deletion failures are propagated
+	testingImpl.StatusContainerFunc = func(podID, containerID string) (vc.ContainerStatus, error) {
+		return vc.ContainerStatus{
+			ID: testContainerID,
+			State: vc.State{
+				State: vc.StateStopped,
+			},
+		}, nil
+	}
+
+	testingImpl.DeleteContainerFunc = func(podID, containerID string) (vc.VCContainer, error) {
+		return nil, errors.New("mock error")
+	}
+
+	_, err = handleContainerRestart(testPodID, testContainerID)
+	assert.Error(err)
+}
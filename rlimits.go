@@ -0,0 +1,41 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+)
+
+// unsupportedRlimits returns the types of the process.rlimits entries
+// that cannot be applied inside the guest. POSIX rlimits are per
+// process and the agent protocol cannot carry them to the workload, so
+// that is all of them: the workload runs with the guest default soft
+// and hard limits. Guest-wide sysctl ceilings are no substitute (they
+// do not change the limits of the workload process), so the divergence
+// from runc behaviour is surfaced to the caller instead of being
+// papered over.
+func unsupportedRlimits(ociSpec oci.CompatOCISpec) []string {
+	if ociSpec.Process == nil {
+		return nil
+	}
+
+	var unsupported []string
+
+	for _, rlimit := range ociSpec.Process.Rlimits {
+		unsupported = append(unsupported, rlimit.Type)
+	}
+
+	return unsupported
+}
@@ -0,0 +1,45 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnsupportedRlimits(t *testing.T) {
+	assert := assert.New(t)
+
+	ociSpec := oci.CompatOCISpec{}
+
+	// no process section
+	assert.Empty(unsupportedRlimits(ociSpec))
+
+	ociSpec.Process = &oci.CompatOCIProcess{}
+
+	// no rlimits
+	assert.Empty(unsupportedRlimits(ociSpec))
+
+	ociSpec.Process.Rlimits = []specs.POSIXRlimit{
+		{Type: "RLIMIT_NOFILE", Hard: 65536, Soft: 1024},
+		{Type: "RLIMIT_NPROC", Hard: 100, Soft: 100},
+	}
+
+	// none of them can be carried to the workload process
+	assert.Equal([]string{"RLIMIT_NOFILE", "RLIMIT_NPROC"}, unsupportedRlimits(ociSpec))
+}
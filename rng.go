@@ -0,0 +1,152 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+)
+
+// Without an entropy device the guest random number generator starts
+// from an empty pool and guest boot can stall on blocking randomness.
+// The VM launch path carries no rng device, so the runtime hot-plugs a
+// virtio-rng device backed by the configured entropy source right
+// after the VM starts, through the QMP control socket qemu exposes for
+// exactly this kind of out-of-band control.
+
+// IDs of the rng backend object and the virtio device attached to the
+// pod VM.
+const (
+	rngObjectID = "rng0"
+	rngDeviceID = "virtio-rng0"
+)
+
+// qmpTimeout bounds the whole QMP exchange so an unresponsive
+// hypervisor cannot hang pod creation.
+const qmpTimeout = 5 * time.Second
+
+// findPodQMPSocket returns the QMP control socket of the pod VM. The
+// socket name embeds the VM UUID, so it is located by pattern.
+func findPodQMPSocket(podID string) (string, error) {
+	matches, err := filepath.Glob(filepath.Join(podRunStoragePath, podID, "ctl-*"))
+	if err != nil {
+		return "", err
+	}
+
+	if len(matches) != 1 {
+		return "", fmt.Errorf("expected 1 QMP control socket for pod %v, found %d", podID, len(matches))
+	}
+
+	return matches[0], nil
+}
+
+// qmpCommand sends a single QMP command and waits for its result,
+// skipping any asynchronous events interleaved with the response.
+func qmpCommand(conn net.Conn, reader *bufio.Reader, command string, arguments map[string]interface{}) error {
+	request := map[string]interface{}{
+		"execute": command,
+	}
+
+	if arguments != nil {
+		request["arguments"] = arguments
+	}
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return err
+		}
+
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(line, &response); err != nil {
+			return err
+		}
+
+		if _, ok := response["event"]; ok {
+			continue
+		}
+
+		if errData, ok := response["error"]; ok {
+			return fmt.Errorf("QMP command %v failed: %s", command, errData)
+		}
+
+		if _, ok := response["return"]; ok {
+			return nil
+		}
+	}
+}
+
+// attachEntropyDevice hot-plugs a virtio-rng device backed by the
+// configured entropy source into the pod VM.
+func attachEntropyDevice(podID string) error {
+	if hypervisorEntropySource == "" {
+		return nil
+	}
+
+	socket, err := findPodQMPSocket(podID)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.DialTimeout("unix", socket, qmpTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(qmpTimeout)); err != nil {
+		return err
+	}
+
+	reader := bufio.NewReader(conn)
+
+	// the QMP greeting comes unsolicited
+	if _, err := reader.ReadBytes('\n'); err != nil {
+		return err
+	}
+
+	if err := qmpCommand(conn, reader, "qmp_capabilities", nil); err != nil {
+		return err
+	}
+
+	if err := qmpCommand(conn, reader, "object-add", map[string]interface{}{
+		"qom-type": "rng-random",
+		"id":       rngObjectID,
+		"props": map[string]interface{}{
+			"filename": hypervisorEntropySource,
+		},
+	}); err != nil {
+		return err
+	}
+
+	return qmpCommand(conn, reader, "device_add", map[string]interface{}{
+		"driver": "virtio-rng-pci",
+		"id":     rngDeviceID,
+		"rng":    rngObjectID,
+	})
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// startFakeQMPServer serves a single client connection on a QMP
+// control socket, sending the greeting and acknowledging every command
+// with an empty return. The commands received are sent down the
+// returned channel.
+func startFakeQMPServer(t *testing.T, socket string) (net.Listener, chan string) {
+	assert := assert.New(t)
+
+	listener, err := net.Listen("unix", socket)
+	assert.NoError(err)
+
+	commandCh := make(chan string, 8)
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		if _, err := conn.Write([]byte(`{"QMP":{"version":{}}}` + "\n")); err != nil {
+			return
+		}
+
+		reader := bufio.NewReader(conn)
+
+		for {
+			line, err := reader.ReadBytes('\n')
+			if err != nil {
+				return
+			}
+
+			var request map[string]json.RawMessage
+			if err := json.Unmarshal(line, &request); err != nil {
+				return
+			}
+
+			var command string
+			if err := json.Unmarshal(request["execute"], &command); err != nil {
+				return
+			}
+
+			commandCh <- command
+
+			if _, err := conn.Write([]byte(`{"return":{}}` + "\n")); err != nil {
+				return
+			}
+		}
+	}()
+
+	return listener, commandCh
+}
+
+func TestFindPodQMPSocket(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+
+	// no pod run directory
+	_, err = findPodQMPSocket(podID)
+	assert.Error(err)
+
+	err = os.MkdirAll(filepath.Join(tmpdir, podID), 0750)
+	assert.NoError(err)
+
+	// no control socket
+	_, err = findPodQMPSocket(podID)
+	assert.Error(err)
+
+	socket := filepath.Join(tmpdir, podID, "ctl-uuid")
+	err = ioutil.WriteFile(socket, []byte{}, 0600)
+	assert.NoError(err)
+
+	found, err := findPodQMPSocket(podID)
+	assert.NoError(err)
+	assert.Equal(socket, found)
+
+	// multiple matches are ambiguous
+	err = ioutil.WriteFile(filepath.Join(tmpdir, podID, "ctl-other"), []byte{}, 0600)
+	assert.NoError(err)
+
+	_, err = findPodQMPSocket(podID)
+	assert.Error(err)
+}
+
+func TestAttachEntropyDevice(t *testing.T) {
+	assert := assert.New(t)
+
+	tmpdir, err := ioutil.TempDir("", "")
+	assert.NoError(err)
+	defer os.RemoveAll(tmpdir)
+
+	savedPodRunStoragePath := podRunStoragePath
+	savedEntropySource := hypervisorEntropySource
+
+	defer func() {
+		podRunStoragePath = savedPodRunStoragePath
+		hypervisorEntropySource = savedEntropySource
+	}()
+
+	podRunStoragePath = tmpdir
+
+	const podID = "pod"
+
+	// no entropy source configured: nothing to attach
+	hypervisorEntropySource = ""
+	assert.NoError(attachEntropyDevice(podID))
+
+	// no QMP socket
+	hypervisorEntropySource = "/dev/urandom"
+	assert.Error(attachEntropyDevice(podID))
+
+	err = os.MkdirAll(filepath.Join(tmpdir, podID), 0750)
+	assert.NoError(err)
+
+	listener, commandCh := startFakeQMPServer(t, filepath.Join(tmpdir, podID, "ctl-uuid"))
+	defer listener.Close()
+
+	err = attachEntropyDevice(podID)
+	assert.NoError(err)
+
+	assert.Equal("qmp_capabilities", <-commandCh)
+	assert.Equal("object-add", <-commandCh)
+	assert.Equal("device_add", <-commandCh)
+}
@@ -62,14 +62,37 @@ func start(containerID string) (vc.VCPod, error) {
 		return nil, err
 	}
 
+	ociSpec, err := oci.GetOCIConfig(status)
+	if err != nil {
+		return nil, err
+	}
+
+	var pod vc.VCPod
+
 	if containerType.IsPod() {
-		return vci.StartPod(podID)
+		pod, err = vci.StartPod(podID)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		c, err := vci.StartContainer(podID, containerID)
+		if err != nil {
+			return nil, err
+		}
+
+		pod = c.Pod()
 	}
 
-	c, err := vci.StartContainer(podID, containerID)
+	// Guest sysctls can only be applied once the agent is reachable,
+	// which is not before the pod runs.
+	sysctls, err := podGuestSysctls(ociSpec)
 	if err != nil {
 		return nil, err
 	}
 
-	return c.Pod(), nil
+	if err := applyGuestSysctls(podID, sysctls); err != nil {
+		return nil, fmt.Errorf("cannot apply guest sysctls: %v", err)
+	}
+
+	return pod, nil
 }
@@ -59,6 +59,10 @@ func TestStartPod(t *testing.T) {
 		MockID: testPodID,
 	}
 
+	configPath := testConfigSetup(t)
+	configJSON, err := readOCIConfigJSON(configPath)
+	assert.NoError(err)
+
 	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
 		return []vc.PodStatus{
 			{
@@ -68,6 +72,7 @@ func TestStartPod(t *testing.T) {
 						ID: pod.ID(),
 						Annotations: map[string]string{
 							vcAnnotations.ContainerTypeKey: string(vc.PodSandbox),
+							vcAnnotations.ConfigJSONKey:    configJSON,
 						},
 					},
 				},
@@ -79,7 +84,7 @@ func TestStartPod(t *testing.T) {
 		testingImpl.ListPodFunc = nil
 	}()
 
-	_, err := start(pod.ID())
+	_, err = start(pod.ID())
 	assert.Error(err)
 	assert.True(vcmock.IsMockError(err))
 
@@ -139,6 +144,10 @@ func TestStartContainerSucessFailure(t *testing.T) {
 		},
 	}
 
+	configPath := testConfigSetup(t)
+	configJSON, err := readOCIConfigJSON(configPath)
+	assert.NoError(err)
+
 	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
 		return []vc.PodStatus{
 			{
@@ -148,6 +157,7 @@ func TestStartContainerSucessFailure(t *testing.T) {
 						ID: testContainerID,
 						Annotations: map[string]string{
 							vcAnnotations.ContainerTypeKey: string(vc.PodContainer),
+							vcAnnotations.ConfigJSONKey:    configJSON,
 						},
 					},
 				},
@@ -159,7 +169,7 @@ func TestStartContainerSucessFailure(t *testing.T) {
 		testingImpl.ListPodFunc = nil
 	}()
 
-	_, err := start(testContainerID)
+	_, err = start(testContainerID)
 	assert.Error(err)
 	assert.True(vcmock.IsMockError(err))
 
@@ -214,6 +224,10 @@ func TestStartCLIFunctionSuccess(t *testing.T) {
 		},
 	}
 
+	configPath := testConfigSetup(t)
+	configJSON, err := readOCIConfigJSON(configPath)
+	assert.NoError(err)
+
 	testingImpl.ListPodFunc = func() ([]vc.PodStatus, error) {
 		return []vc.PodStatus{
 			{
@@ -223,6 +237,7 @@ func TestStartCLIFunctionSuccess(t *testing.T) {
 						ID: testContainerID,
 						Annotations: map[string]string{
 							vcAnnotations.ContainerTypeKey: string(vc.PodContainer),
+							vcAnnotations.ConfigJSONKey:    configJSON,
 						},
 					},
 				},
@@ -249,6 +264,6 @@ func TestStartCLIFunctionSuccess(t *testing.T) {
 	ctx := cli.NewContext(app, flagSet, nil)
 	assert.NotNil(ctx)
 
-	err := fn(ctx)
+	err = fn(ctx)
 	assert.NoError(err)
 }
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/kata-containers/runtime/virtcontainers/pkg/oci"
 	"github.com/urfave/cli"
@@ -44,7 +45,7 @@ instance of a container.`,
 
 func state(containerID string) error {
 	// Checks the MUST and MUST NOT from OCI runtime specification
-	status, _, err := getExistingContainerInfo(containerID)
+	status, podID, err := getExistingContainerInfo(containerID)
 	if err != nil {
 		return err
 	}
@@ -52,6 +53,25 @@ func state(containerID string) error {
 	// Convert the status to the expected State structure
 	state := oci.StatusToOCIState(status)
 
+	// Report the host cgroup holding the pod VM, shim and proxy, so
+	// that callers can account for the sandbox overhead.
+	if enableSandboxCgroup {
+		if state.Annotations == nil {
+			state.Annotations = map[string]string{}
+		}
+
+		state.Annotations[sandboxCgroupAnnotation] = filepath.Join(name, podID)
+	}
+
+	// Report the agent details so callers can correlate container
+	// failures with a wedged or mismatched agent.
+	if state.Annotations == nil {
+		state.Annotations = map[string]string{}
+	}
+
+	state.Annotations[agentVersionAnnotation] = agentVersion
+	state.Annotations[agentHealthAnnotation] = probeAgentHealth(podID)
+
 	stateJSON, err := json.MarshalIndent(state, "", "  ")
 	if err != nil {
 		return err
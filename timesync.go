@@ -0,0 +1,43 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	vc "github.com/kata-containers/runtime/virtcontainers"
+)
+
+// ptpKvmModule is the guest driver that exposes the host clock to the
+// guest as a PTP device (/dev/ptp0).
+const ptpKvmModule = "ptp_kvm"
+
+// time synchronization setting from the [runtime] section of the
+// configuration file. Long-running pods drift by seconds per day
+// without it, which breaks time-sensitive workloads such as TLS
+// certificate validation and Kerberos.
+var enableTimeSync = false
+
+// timeSyncKernelParams returns the extra guest kernel parameters used
+// when guest time synchronization is enabled. Loading ptp_kvm at boot
+// gives the time daemon in the guest image a /dev/ptp0 reference clock
+// backed by the host clock, which it prefers over (unreachable) NTP
+// servers.
+func timeSyncKernelParams() []vc.Param {
+	return []vc.Param{
+		{
+			Key:   "modules-load",
+			Value: ptpKvmModule,
+		},
+	}
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2018 Intel Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeSyncKernelParams(t *testing.T) {
+	assert := assert.New(t)
+
+	params := timeSyncKernelParams()
+	assert.NotEmpty(params)
+
+	foundModule := false
+
+	for _, p := range params {
+		assert.NotEmpty(p.Key)
+		assert.NotEmpty(p.Value)
+
+		if p.Key == "modules-load" && p.Value == ptpKvmModule {
+			foundModule = true
+		}
+	}
+
+	assert.True(foundModule)
+}
+
+func TestTimeSyncModuleWhitelisted(t *testing.T) {
+	assert := assert.New(t)
+
+	// pods must also be able to request the module explicitly
+	assert.True(guestModulesWhitelist[ptpKvmModule])
+}